@@ -0,0 +1,251 @@
+package goscalar
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	// Asset endpoints, relative to the mount prefix
+	assetsPath     = "assets/"
+	scriptBaseName = "api-reference"
+
+	// Content types
+	contentTypeJS = "text/javascript; charset=utf-8"
+
+	// Cache settings
+	immutableCacheControl = "public, max-age=31536000, immutable"
+
+	// Content encodings
+	encodingIdentity = "identity"
+	encodingGzip     = "gzip"
+	encodingBrotli   = "br"
+
+	// Compression levels, assets are compressed once per process
+	brotliLevel = 9
+)
+
+var (
+	// scriptAsset is the embedded Scalar bundle, hashed on first use
+	scriptAsset = sync.OnceValue(func() *asset {
+		return newAsset(scriptBaseName, ".js", contentTypeJS, immutableCacheControl, []byte(embedScript))
+	})
+
+	// inlineScriptTag is the script element with the whole bundle inlined, built
+	// once since the bundle weighs several megabytes
+	inlineScriptTag = sync.OnceValue(func() template.HTML {
+		return template.HTML("<script>" + embedScript + "</script>")
+	})
+)
+
+// asset is an in-memory response body served with a strong ETag and precompressed variants.
+// The compressed variants are built lazily, the first time a client accepts them.
+type asset struct {
+	name         string // Content-hashed file name, e.g. api-reference.0123456789abcdef.js
+	contentType  string
+	cacheControl string
+	body         []byte
+	hash         string
+	modTime      time.Time // Sent as Last-Modified when set
+
+	gzipBody   func() []byte
+	brotliBody func() []byte
+
+	compressOnReuse bool        // Served uncompressed the first time, as it may never be served again
+	served          atomic.Bool // Whether the asset was served before
+}
+
+// newAsset creates an asset named after the SHA-256 of its body
+func newAsset(baseName, extension, contentType, cacheControl string, body []byte) *asset {
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+
+	return &asset{
+		name:         fmt.Sprintf("%s.%s%s", baseName, hash[:16], extension),
+		contentType:  contentType,
+		cacheControl: cacheControl,
+		body:         body,
+		hash:         hash,
+		gzipBody:     sync.OnceValue(func() []byte { return gzipCompress(body) }),
+		brotliBody:   sync.OnceValue(func() []byte { return brotliCompress(body) }),
+	}
+}
+
+// etag returns the strong ETag of the given encoding, each representation has its own tag
+func (a *asset) etag(encoding string) string {
+	if encoding == encodingIdentity {
+		return strconv.Quote(a.hash)
+	}
+	return strconv.Quote(a.hash + "-" + encoding)
+}
+
+// serve writes the asset, negotiating the content encoding and honoring
+// If-None-Match and If-Modified-Since
+func (a *asset) serve(w http.ResponseWriter, r *http.Request) {
+	encoding := encodingIdentity
+	if reused := a.served.Swap(true); reused || !a.compressOnReuse {
+		encoding = negotiateEncoding(r.Header.Get("Accept-Encoding"))
+	}
+
+	header := w.Header()
+	header.Add("Vary", "Accept-Encoding")
+	header.Set("Cache-Control", a.cacheControl)
+	header.Set("ETag", a.etag(encoding))
+	if !a.modTime.IsZero() {
+		header.Set("Last-Modified", a.modTime.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, a.etag(encoding), a.modTime) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body := a.body
+	switch encoding {
+	case encodingGzip:
+		body = a.gzipBody()
+	case encodingBrotli:
+		body = a.brotliBody()
+	}
+	if encoding != encodingIdentity {
+		header.Set("Content-Encoding", encoding)
+	}
+	writeBody(w, r, a.contentType, body)
+}
+
+// negotiateEncoding picks the best supported encoding from an Accept-Encoding header,
+// preferring brotli over gzip when both have the same quality
+func negotiateEncoding(acceptEncoding string) string {
+	var (
+		best        = encodingIdentity
+		bestQuality = 0.0
+		wildcard    = -1.0
+		qualities   = map[string]float64{}
+	)
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		if coding == "*" {
+			wildcard = quality
+			continue
+		}
+		qualities[coding] = quality
+	}
+
+	for _, encoding := range []string{encodingBrotli, encodingGzip} {
+		quality, ok := qualities[encoding]
+		if !ok && wildcard >= 0 {
+			quality, ok = wildcard, true
+		}
+		if ok && quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+// etagMatches reports whether an If-None-Match header matches the ETag,
+// using the weak comparison required by RFC 9110
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipCompress compresses the body with the best gzip compression
+func gzipCompress(body []byte) []byte {
+	var buf bytes.Buffer
+	writer, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	writer.Write(body)
+	writer.Close()
+	return buf.Bytes()
+}
+
+// brotliCompress compresses the body with brotli
+func brotliCompress(body []byte) []byte {
+	var buf bytes.Buffer
+	writer := brotli.NewWriterLevel(&buf, brotliLevel)
+	writer.Write(body)
+	writer.Close()
+	return buf.Bytes()
+}
+
+// scriptTag returns the script element loading the Scalar bundle, either
+// referencing the cacheable asset or with the whole bundle inlined
+func scriptTag(inline bool) template.HTML {
+	if inline {
+		return inlineScriptTag()
+	}
+	return scriptTagAt("")
+}
+
+// scriptTagAt returns the script element referencing the bundle under the given
+// base, relative to the page when it is empty
+func scriptTagAt(base string) template.HTML {
+	src := base + assetsPath + scriptAsset().name
+	return template.HTML(fmt.Sprintf(`<script src="%s"></script>`, template.HTMLEscapeString(src)))
+}
+
+// script returns the script element of a page linking the bundle from base, or
+// inlining it for standalone pages. Inlined and custom scripts are kept as they are.
+func (c *Config) script(base string) template.HTML {
+	switch {
+	case c.Script != scriptTag(false):
+		return c.Script
+	case base == standalonePage:
+		return scriptTag(true)
+	case base == "":
+		return c.Script
+	default:
+		return scriptTagAt(base)
+	}
+}
+
+// WithInlineScript embeds the Scalar bundle in the pages served by the handler
+// instead of referencing the cacheable asset. RenderDocs always inlines it.
+func WithInlineScript() Option {
+	return func(s *Scalar) error {
+		s.config.Script = scriptTag(true)
+		return nil
+	}
+}
+
+// InlineScript embeds the Scalar bundle in the page
+func (b *Builder) InlineScript() *Builder {
+	b.options = append(b.options, WithInlineScript())
+	return b
+}
@@ -0,0 +1,220 @@
+package goscalar
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		expected       string
+	}{
+		{name: "empty header", acceptEncoding: "", expected: encodingIdentity},
+		{name: "gzip only", acceptEncoding: "gzip", expected: encodingGzip},
+		{name: "brotli preferred", acceptEncoding: "gzip, deflate, br", expected: encodingBrotli},
+		{name: "quality wins", acceptEncoding: "br;q=0.5, gzip;q=0.8", expected: encodingGzip},
+		{name: "disabled brotli", acceptEncoding: "br;q=0, gzip", expected: encodingGzip},
+		{name: "wildcard", acceptEncoding: "*", expected: encodingBrotli},
+		{name: "wildcard with exclusion", acceptEncoding: "*;q=1, br;q=0", expected: encodingGzip},
+		{name: "unsupported only", acceptEncoding: "deflate, zstd", expected: encodingIdentity},
+		{name: "case insensitive", acceptEncoding: "GZIP", expected: encodingGzip},
+		{name: "invalid quality", acceptEncoding: "br;q=abc, gzip", expected: encodingGzip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, negotiateEncoding(tt.acceptEncoding))
+		})
+	}
+}
+
+func Test_ETagMatches(t *testing.T) {
+	etag := `"abc"`
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		expected    bool
+	}{
+		{name: "empty header", ifNoneMatch: "", expected: false},
+		{name: "exact match", ifNoneMatch: `"abc"`, expected: true},
+		{name: "weak match", ifNoneMatch: `W/"abc"`, expected: true},
+		{name: "list match", ifNoneMatch: `"xyz", "abc"`, expected: true},
+		{name: "wildcard", ifNoneMatch: "*", expected: true},
+		{name: "no match", ifNoneMatch: `"xyz"`, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, etagMatches(tt.ifNoneMatch, etag))
+		})
+	}
+}
+
+func Test_AssetServe(t *testing.T) {
+	body := []byte(strings.Repeat("console.log('scalar');\n", 100))
+	testAsset := newAsset("test", ".js", contentTypeJS, immutableCacheControl, body)
+
+	require.True(t, strings.HasPrefix(testAsset.name, "test."))
+	require.True(t, strings.HasSuffix(testAsset.name, ".js"))
+
+	tests := []struct {
+		name             string
+		method           string
+		acceptEncoding   string
+		ifNoneMatch      string
+		expectedStatus   int
+		expectedEncoding string
+	}{
+		{
+			name:           "identity",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:             "gzip",
+			method:           http.MethodGet,
+			acceptEncoding:   "gzip",
+			expectedStatus:   http.StatusOK,
+			expectedEncoding: encodingGzip,
+		},
+		{
+			name:             "brotli",
+			method:           http.MethodGet,
+			acceptEncoding:   "gzip, br",
+			expectedStatus:   http.StatusOK,
+			expectedEncoding: encodingBrotli,
+		},
+		{
+			name:           "not modified",
+			method:         http.MethodGet,
+			ifNoneMatch:    testAsset.etag(encodingIdentity),
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			name:           "gzip ETag does not match identity",
+			method:         http.MethodGet,
+			ifNoneMatch:    testAsset.etag(encodingGzip),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:             "HEAD",
+			method:           http.MethodHead,
+			acceptEncoding:   "gzip",
+			expectedStatus:   http.StatusOK,
+			expectedEncoding: encodingGzip,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			rec := httptest.NewRecorder()
+
+			testAsset.serve(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			require.Equal(t, immutableCacheControl, rec.Header().Get("Cache-Control"))
+			require.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+			require.NotEmpty(t, rec.Header().Get("ETag"))
+			require.False(t, strings.HasPrefix(rec.Header().Get("ETag"), "W/"))
+
+			if tt.expectedStatus == http.StatusNotModified || tt.method == http.MethodHead {
+				require.Empty(t, rec.Body.Bytes())
+				return
+			}
+
+			require.Equal(t, contentTypeJS, rec.Header().Get("Content-Type"))
+			require.Equal(t, tt.expectedEncoding, rec.Header().Get("Content-Encoding"))
+			require.Equal(t, body, decodeBody(t, tt.expectedEncoding, rec.Body.Bytes()))
+		})
+	}
+}
+
+func Test_ScriptAsset(t *testing.T) {
+	validContent := `{"openapi": "3.0.0", "info": {"title": "Test API", "version": "1.0.0"}}`
+
+	t.Run("page references the asset", func(t *testing.T) {
+		scalar, err := NewScalar(WithSpecContent(validContent))
+		require.NoError(t, err)
+
+		page := httptest.NewRecorder()
+		scalar.ServeHTTP(page, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Contains(t, page.Body.String(), `<script src="assets/`+scriptAsset().name+`"></script>`)
+		require.Less(t, page.Body.Len(), 10_000)
+
+		req := httptest.NewRequest(http.MethodGet, "/assets/"+scriptAsset().name, nil)
+		rec := httptest.NewRecorder()
+		scalar.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, immutableCacheControl, rec.Header().Get("Cache-Control"))
+		require.Equal(t, embedScript, rec.Body.String())
+	})
+
+	t.Run("unknown asset", func(t *testing.T) {
+		scalar, err := NewScalar(WithSpecContent(validContent))
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/assets/api-reference.0000000000000000.js", nil)
+		rec := httptest.NewRecorder()
+		scalar.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("RenderDocs is self-contained", func(t *testing.T) {
+		scalar, err := NewScalar(WithSpecContent(validContent))
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, scalar.RenderDocs(&buf))
+		require.NotContains(t, buf.String(), `<script src="assets/`)
+		require.Contains(t, buf.String(), embedScript[:200])
+	})
+
+	t.Run("inline script", func(t *testing.T) {
+		scalar, err := NewBuilder().Content(validContent).InlineScript().Build()
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		scalar.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.NotContains(t, rec.Body.String(), `<script src="assets/`)
+		require.Contains(t, rec.Body.String(), embedScript[:200])
+	})
+}
+
+// decodeBody decompresses a response body with the given content encoding
+func decodeBody(t *testing.T, encoding string, body []byte) []byte {
+	t.Helper()
+
+	var reader io.Reader = bytes.NewReader(body)
+	switch encoding {
+	case encodingGzip:
+		gzipReader, err := gzip.NewReader(reader)
+		require.NoError(t, err)
+		reader = gzipReader
+	case encodingBrotli:
+		reader = brotli.NewReader(reader)
+	}
+
+	decoded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return decoded
+}
@@ -0,0 +1,178 @@
+package goscalar
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidHeader is returned for request headers that cannot be sent
+var ErrInvalidHeader = errors.New("invalid request header")
+
+// HeaderFunc sets headers on every URL request, e.g. a token that rotates.
+// Its errors are returned as is, so they should not hold credentials.
+type HeaderFunc func(ctx context.Context, header http.Header) error
+
+// WithHeader adds a header to the requests of WithURL
+func WithHeader(key, value string) Option {
+	return func(s *Scalar) error {
+		if !validHeaderKey(key) {
+			return fmt.Errorf("%w: %q is not a valid header name", ErrInvalidHeader, key)
+		}
+		// The value is left out of the error, it may be a credential
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return fmt.Errorf("%w: value of %q contains control characters", ErrInvalidHeader, key)
+		}
+		if s.urlHeader == nil {
+			s.urlHeader = http.Header{}
+		}
+		s.urlHeader.Add(key, value)
+		return nil
+	}
+}
+
+// WithBasicAuth authenticates the requests of WithURL with HTTP basic auth
+func WithBasicAuth(username, password string) Option {
+	return func(s *Scalar) error {
+		if username == "" || strings.Contains(username, ":") {
+			return fmt.Errorf("%w: basic auth username must be set and cannot contain ':'", ErrInvalidHeader)
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		return setAuthorization(s, "Basic "+credentials)
+	}
+}
+
+// WithBearerToken authenticates the requests of WithURL with a bearer token
+func WithBearerToken(token string) Option {
+	return func(s *Scalar) error {
+		if strings.TrimSpace(token) == "" {
+			return fmt.Errorf("%w: bearer token cannot be empty", ErrInvalidHeader)
+		}
+		return setAuthorization(s, "Bearer "+token)
+	}
+}
+
+// WithHeaderFunc sets headers on every request of WithURL, including refreshes
+func WithHeaderFunc(fn HeaderFunc) Option {
+	return func(s *Scalar) error {
+		s.urlHeaderFunc = fn
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent of the requests of WithURL
+func WithUserAgent(userAgent string) Option {
+	return func(s *Scalar) error {
+		userAgent = strings.TrimSpace(userAgent)
+		if userAgent == "" || strings.ContainsAny(userAgent, "\r\n\x00") {
+			return fmt.Errorf("%w: invalid User-Agent", ErrInvalidHeader)
+		}
+		s.userAgent = userAgent
+		return nil
+	}
+}
+
+// Header adds a header to URL requests
+func (b *Builder) Header(key, value string) *Builder {
+	b.options = append(b.options, WithHeader(key, value))
+	return b
+}
+
+// BasicAuth authenticates URL requests with HTTP basic auth
+func (b *Builder) BasicAuth(username, password string) *Builder {
+	b.options = append(b.options, WithBasicAuth(username, password))
+	return b
+}
+
+// BearerToken authenticates URL requests with a bearer token
+func (b *Builder) BearerToken(token string) *Builder {
+	b.options = append(b.options, WithBearerToken(token))
+	return b
+}
+
+// HeaderFunc sets headers on every URL request
+func (b *Builder) HeaderFunc(fn HeaderFunc) *Builder {
+	b.options = append(b.options, WithHeaderFunc(fn))
+	return b
+}
+
+// UserAgent sets the User-Agent of URL requests
+func (b *Builder) UserAgent(userAgent string) *Builder {
+	b.options = append(b.options, WithUserAgent(userAgent))
+	return b
+}
+
+// setAuthorization replaces the Authorization header of URL requests
+func setAuthorization(s *Scalar, value string) error {
+	if strings.ContainsAny(value, "\r\n\x00") {
+		return fmt.Errorf("%w: credentials contain control characters", ErrInvalidHeader)
+	}
+	if s.urlHeader == nil {
+		s.urlHeader = http.Header{}
+	}
+	s.urlHeader.Set("Authorization", value)
+	return nil
+}
+
+// callerHeaders returns the headers the caller set or changed on top of the defaults,
+// the User-Agent aside since it carries no credentials
+func callerHeaders(defaults, header http.Header) []string {
+	var keys []string
+	for key, values := range header {
+		if key != "User-Agent" && !slices.Equal(defaults[key], values) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// stripOnRedirect returns a copy of client that restores the given headers to their
+// defaults on redirects leaving the host of the original request, so the headers of
+// WithHeader and WithHeaderFunc never reach another host
+func stripOnRedirect(client *http.Client, defaults http.Header, keys []string) *http.Client {
+	if len(keys) == 0 {
+		return client
+	}
+
+	redirectClient := *client
+	redirectClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+			for _, key := range keys {
+				if values, ok := defaults[key]; ok {
+					req.Header[key] = slices.Clone(values)
+				} else {
+					req.Header.Del(key)
+				}
+			}
+		}
+		if client.CheckRedirect != nil {
+			return client.CheckRedirect(req, via)
+		}
+		// Same limit as the default policy of http.Client
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &redirectClient
+}
+
+// validHeaderKey reports whether a header name is an RFC 7230 token
+func validHeaderKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
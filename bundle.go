@@ -0,0 +1,577 @@
+package goscalar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// componentNamePattern matches the characters allowed in component names
+	componentNamePattern = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+	// Errors
+	ErrDanglingRef = errors.New("dangling reference")
+	ErrRefCycle    = errors.New("reference cycle")
+)
+
+// RefError describes an external $ref that could not be bundled
+type RefError struct {
+	File string // File containing the reference
+	Ref  string // Reference as written in the file
+	Err  error
+}
+
+// Error returns the reference, the file containing it and the reason
+func (e *RefError) Error() string {
+	return fmt.Sprintf("%s: $ref %q: %v", e.File, e.Ref, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *RefError) Unwrap() error {
+	return e.Err
+}
+
+// specFiles reads the files a multi-file spec is split into
+type specFiles interface {
+	// read returns the content of a file
+	read(name string) ([]byte, error)
+	// resolve returns the name of a file referenced from the base file
+	resolve(base, ref string) (string, error)
+}
+
+// osFiles reads spec files from the OS file system, names are absolute paths
+type osFiles struct{}
+
+func (osFiles) read(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (osFiles) resolve(base, ref string) (string, error) {
+	ref = filepath.FromSlash(ref)
+	if filepath.IsAbs(ref) {
+		return filepath.Clean(ref), nil
+	}
+	return filepath.Join(filepath.Dir(base), ref), nil
+}
+
+// trackedFiles records the names of the files read, so they can be watched
+type trackedFiles struct {
+	specFiles
+	names []string
+}
+
+func (f *trackedFiles) read(name string) ([]byte, error) {
+	if !slices.Contains(f.names, name) {
+		f.names = append(f.names, name)
+	}
+	return f.specFiles.read(name)
+}
+
+// fsFiles reads spec files from an fs.FS, names are slash-separated fs paths
+type fsFiles struct {
+	fsys fs.FS
+}
+
+func (f fsFiles) read(name string) ([]byte, error) {
+	return fs.ReadFile(f.fsys, name)
+}
+
+func (f fsFiles) resolve(base, ref string) (string, error) {
+	name := path.Join(path.Dir(base), ref)
+	if path.IsAbs(ref) || !fs.ValidPath(name) {
+		return "", fmt.Errorf("%s is outside of the file system", ref)
+	}
+	return name, nil
+}
+
+// bundler inlines the external $refs of a spec into its components
+type bundler struct {
+	ctx        context.Context
+	files      specFiles
+	root       string
+	swagger    bool
+	docs       map[string]any    // Parsed files, by name
+	targets    map[string]string // Local refs of the bundled targets, by file and pointer
+	aliases    map[string]string // Bundled components that only hold a local $ref
+	origins    map[string]string // Files the bundled components come from, by local ref
+	inlining   map[string]bool   // Path items being inlined
+	bundled    map[*object]bool  // Components whose refs were already resolved
+	components *object           // Object holding the component sections
+	errs       []error
+}
+
+// bundleSpec parses the root file of a spec and bundles the files referenced with relative
+// $refs under its components. Referenced files may be JSON or YAML. Components defined in
+// referenced files keep their name when it is free, whole files are named after the file.
+// Path items are inlined, as components cannot hold them in every OpenAPI version.
+func bundleSpec(ctx context.Context, files specFiles, name string, data []byte) (string, error) {
+	content, err := parseSpecContent(string(data))
+	if err != nil {
+		return "", err
+	}
+
+	tree, err := decodeTree(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	root, ok := tree.(*object)
+	if !ok || !hasExternalRefs(root) {
+		return content, nil
+	}
+
+	b := &bundler{
+		ctx:        ctx,
+		files:      files,
+		root:       name,
+		swagger:    root.str("swagger") != "",
+		docs:       map[string]any{name: root},
+		targets:    map[string]string{},
+		aliases:    map[string]string{},
+		origins:    map[string]string{},
+		inlining:   map[string]bool{},
+		bundled:    map[*object]bool{},
+		components: root,
+	}
+	_, hasComponents := root.get("components")
+	if !b.swagger {
+		b.components = root.ensureChild("components")
+	}
+
+	b.resolveRefs(root, name, nil)
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	b.checkAliases()
+	if len(b.errs) > 0 {
+		return "", errors.Join(b.errs...)
+	}
+
+	if !hasComponents && len(b.components.keys) == 0 {
+		root.delete("components")
+	}
+	return encodeTree(root)
+}
+
+// hasExternalRefs reports whether a spec references other files
+func hasExternalRefs(root *object) bool {
+	found := false
+	walkTree(root, func(obj *object) {
+		if ref, ok := obj.values["$ref"].(string); ok && isFileRef(ref) {
+			found = true
+		}
+	})
+	return found
+}
+
+// isFileRef reports whether a $ref points at another file rather than a URL or the same document
+func isFileRef(ref string) bool {
+	if strings.HasPrefix(ref, "#") {
+		return false
+	}
+	parsed, err := url.Parse(ref)
+	return err != nil || parsed.Scheme == "" || len(parsed.Scheme) == 1 // Windows drive letters
+}
+
+// resolveRefs rewrites the $refs found in a value of the given file. The path holds the
+// keys leading to the value, it tells which component section a referenced file belongs to.
+func (b *bundler) resolveRefs(value any, file string, location []string) {
+	switch v := value.(type) {
+	case *object:
+		if b.bundled[v] {
+			return
+		}
+		if ref, ok := v.values["$ref"].(string); ok {
+			b.resolveRef(v, ref, file, location)
+			return
+		}
+		for _, key := range v.keys {
+			b.resolveRefs(v.values[key], file, append(location, key))
+		}
+	case []any:
+		for i, item := range v {
+			b.resolveRefs(item, file, append(location, strconv.Itoa(i)))
+		}
+	}
+}
+
+// resolveRef rewrites a single $ref into a local one, bundling its target
+func (b *bundler) resolveRef(obj *object, ref, file string, location []string) {
+	// Local refs of the root file already point at the bundled document
+	if strings.HasPrefix(ref, "#") && file == b.root {
+		return
+	}
+	if !strings.HasPrefix(ref, "#") && !isFileRef(ref) {
+		return
+	}
+
+	refFile, pointer, _ := strings.Cut(ref, "#")
+	target := file
+	if refFile != "" {
+		unescaped, err := url.PathUnescape(refFile)
+		if err != nil {
+			b.fail(file, ref, fmt.Errorf("%w: %v", ErrDanglingRef, err))
+			return
+		}
+		if target, err = b.files.resolve(file, unescaped); err != nil {
+			b.fail(file, ref, fmt.Errorf("%w: %v", ErrDanglingRef, err))
+			return
+		}
+	}
+
+	// Path items are inlined with their refs resolved against their own file
+	if b.isPathItem(location) {
+		b.inlinePathItem(obj, ref, file, target, pointer, location)
+		return
+	}
+	if file == b.root && b.isComponentEntry(location) {
+		b.inlineComponent(obj, ref, file, target, pointer, location)
+		return
+	}
+
+	local, ok := b.bundle(file, ref, target, pointer, location)
+	if ok {
+		obj.values["$ref"] = local
+	}
+}
+
+// bundle adds the target of a reference to the components and returns its local ref
+func (b *bundler) bundle(file, ref, target, pointer string, location []string) (string, bool) {
+	// References back into the root file only need the pointer
+	if target == b.root {
+		if _, err := b.lookup(target, pointer); err != nil {
+			b.fail(file, ref, err)
+			return "", false
+		}
+		return "#" + pointer, true
+	}
+
+	key := target + "#" + pointer
+	if local, ok := b.targets[key]; ok {
+		return local, true
+	}
+
+	value, err := b.lookup(target, pointer)
+	if err != nil {
+		b.fail(file, ref, err)
+		return "", false
+	}
+
+	section, name := b.componentName(target, pointer, location)
+	sectionObj := b.components.ensureChild(section)
+	name = b.freeName(sectionObj, name)
+
+	local := "#/" + section + "/" + escapePointer(name)
+	if !b.swagger {
+		local = "#/components/" + section + "/" + escapePointer(name)
+	}
+
+	component := cloneTree(value)
+	sectionObj.set(name, component)
+
+	componentLocation := []string{section, name}
+	if !b.swagger {
+		componentLocation = []string{"components", section, name}
+	}
+	b.expand(component, key, local, target, componentLocation)
+	return local, true
+}
+
+// inlineComponent replaces a component entry of the root file referencing another file
+// with the referenced value, so the component keeps its name
+func (b *bundler) inlineComponent(obj *object, ref, file, target, pointer string, location []string) {
+	key := target + "#" + pointer
+	if local, ok := b.targets[key]; ok || target == b.root {
+		if !ok {
+			local, ok = b.bundle(file, ref, target, pointer, location)
+		}
+		if ok {
+			obj.values["$ref"] = local
+		}
+		return
+	}
+
+	value, err := b.lookup(target, pointer)
+	if err != nil {
+		b.fail(file, ref, err)
+		return
+	}
+	component, ok := cloneTree(value).(*object)
+	if !ok {
+		b.fail(file, ref, fmt.Errorf("%w: component is not an object", ErrDanglingRef))
+		return
+	}
+
+	tokens := make([]string, len(location))
+	for i, token := range location {
+		tokens[i] = escapePointer(token)
+	}
+
+	obj.keys, obj.values = component.keys, component.values
+	b.expand(obj, key, "#/"+strings.Join(tokens, "/"), target, location)
+}
+
+// expand registers a bundled target under its local ref and resolves its own refs.
+// The target is registered first, so recursive schemas reference themselves.
+func (b *bundler) expand(component any, key, local, target string, location []string) {
+	b.targets[key] = local
+	b.origins[local] = target
+	b.resolveRefs(component, target, location)
+
+	obj, ok := component.(*object)
+	if !ok {
+		return
+	}
+	b.bundled[obj] = true
+	if len(obj.keys) == 1 {
+		if next, ok := obj.values["$ref"].(string); ok {
+			b.aliases[local] = next
+		}
+	}
+}
+
+// inlinePathItem replaces a path item $ref with the referenced path item
+func (b *bundler) inlinePathItem(obj *object, ref, file, target, pointer string, location []string) {
+	key := target + "#" + pointer
+	if b.inlining[key] {
+		b.fail(file, ref, fmt.Errorf("%w: path item references itself", ErrRefCycle))
+		return
+	}
+
+	value, err := b.lookup(target, pointer)
+	if err != nil {
+		b.fail(file, ref, err)
+		return
+	}
+	item, ok := cloneTree(value).(*object)
+	if !ok {
+		b.fail(file, ref, fmt.Errorf("%w: path item is not an object", ErrDanglingRef))
+		return
+	}
+
+	b.inlining[key] = true
+	defer delete(b.inlining, key)
+
+	obj.keys, obj.values = item.keys, item.values
+	b.resolveRefs(obj, target, location)
+}
+
+// isPathItem reports whether a location holds a path item, e.g. /paths/~1pets
+func (b *bundler) isPathItem(location []string) bool {
+	return len(location) == 2 && (location[0] == "paths" || location[0] == "webhooks")
+}
+
+// isComponentEntry reports whether a location holds a component, e.g. /components/schemas/Pet
+func (b *bundler) isComponentEntry(location []string) bool {
+	if b.swagger {
+		return len(location) == 2 && isComponentSection(location[0], true)
+	}
+	return len(location) == 3 && location[0] == "components" && isComponentSection(location[1], false)
+}
+
+// lookup returns the value a JSON pointer designates in a file, loading it when needed
+func (b *bundler) lookup(file, pointer string) (any, error) {
+	doc, ok := b.docs[file]
+	if !ok {
+		if err := b.ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := b.files.read(file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDanglingRef, err)
+		}
+		content, err := parseSpecContent(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		if doc, err = decodeTree(content); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		b.docs[file] = doc
+	}
+
+	value, ok := resolvePointer(doc, pointer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has nothing at %q", ErrDanglingRef, file, pointer)
+	}
+	return value, nil
+}
+
+// componentName returns the section and the preferred name of a bundled target.
+// Targets that are components in their own file keep their section and name.
+func (b *bundler) componentName(target, pointer string, location []string) (string, string) {
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	if b.swagger && len(tokens) == 2 && isComponentSection(tokens[0], true) {
+		return tokens[0], sanitizeComponentName(unescapePointer(tokens[1]))
+	}
+	if !b.swagger && len(tokens) == 3 && tokens[0] == "components" && isComponentSection(tokens[1], false) {
+		return tokens[1], sanitizeComponentName(unescapePointer(tokens[2]))
+	}
+
+	name := strings.TrimSuffix(path.Base(filepath.ToSlash(target)), path.Ext(target))
+	if pointer != "" {
+		name = unescapePointer(tokens[len(tokens)-1])
+	}
+	return b.sectionFor(location), sanitizeComponentName(name)
+}
+
+// sectionFor infers the component section of a $ref from where it appears
+func (b *bundler) sectionFor(location []string) string {
+	n := len(location)
+	last, parent := "", ""
+	if n > 0 {
+		last = location[n-1]
+	}
+	if n > 1 {
+		parent = location[n-2]
+	}
+
+	// Component entries keep their section
+	if b.isComponentEntry(location) {
+		return location[n-2]
+	}
+
+	_, indexErr := strconv.Atoi(last)
+	inOperation := n > 2 && isHTTPMethod(location[n-3])
+
+	if b.swagger {
+		switch {
+		case parent == "parameters" && indexErr == nil:
+			return "parameters"
+		case parent == "responses" && inOperation:
+			return "responses"
+		default:
+			return "definitions"
+		}
+	}
+
+	switch {
+	case parent == "parameters" && indexErr == nil:
+		return "parameters"
+	case parent == "responses" && inOperation:
+		return "responses"
+	case last == "requestBody":
+		return "requestBodies"
+	case parent == "headers":
+		return "headers"
+	case parent == "examples":
+		return "examples"
+	case parent == "links":
+		return "links"
+	case parent == "callbacks":
+		return "callbacks"
+	default:
+		return "schemas"
+	}
+}
+
+// freeName returns the name, suffixed with a number when the section already holds it
+func (b *bundler) freeName(section *object, name string) string {
+	candidate := name
+	for i := 2; ; i++ {
+		if _, taken := section.get(candidate); !taken {
+			return candidate
+		}
+		candidate = name + strconv.Itoa(i)
+	}
+}
+
+// checkAliases reports components that only reference each other in a loop
+func (b *bundler) checkAliases() {
+	starts := make([]string, 0, len(b.aliases))
+	for local := range b.aliases {
+		starts = append(starts, local)
+	}
+	sort.Strings(starts)
+
+	reported := map[string]bool{}
+	for _, start := range starts {
+		seen := map[string]bool{}
+		ref := start
+		for ref != "" && !seen[ref] {
+			seen[ref] = true
+			ref = b.aliases[ref]
+		}
+		if ref == "" || reported[ref] {
+			continue
+		}
+
+		// Follow the loop once more to list its members
+		var members []string
+		for member := ref; !reported[member]; member = b.aliases[member] {
+			reported[member] = true
+			members = append(members, member)
+		}
+		b.errs = append(b.errs, &RefError{
+			File: b.origins[ref],
+			Ref:  b.aliases[ref],
+			Err:  fmt.Errorf("%w: %s", ErrRefCycle, strings.Join(append(members, ref), " -> ")),
+		})
+	}
+}
+
+// fail records a reference that could not be bundled
+func (b *bundler) fail(file, ref string, err error) {
+	b.errs = append(b.errs, &RefError{File: file, Ref: ref, Err: err})
+}
+
+// resolvePointer returns the value a JSON pointer designates in a decoded tree
+func resolvePointer(doc any, pointer string) (any, bool) {
+	if pointer == "" || pointer == "/" {
+		return doc, true
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, false
+	}
+
+	value := doc
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = unescapePointer(token)
+		switch v := value.(type) {
+		case *object:
+			next, ok := v.get(token)
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, false
+			}
+			value = v[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// isComponentSection reports whether a key is a component section of a spec family
+func isComponentSection(key string, swagger bool) bool {
+	for _, section := range componentSections(swagger) {
+		if section[len(section)-1] == key {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeComponentName replaces the characters component names cannot hold
+func sanitizeComponentName(name string) string {
+	name = componentNamePattern.ReplaceAllString(name, "_")
+	if name == "" {
+		return "component"
+	}
+	return name
+}
@@ -0,0 +1,243 @@
+package goscalar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeSpecFiles writes the files of a multi-file spec into a temporary directory
+func writeSpecFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func Test_BundleSpec(t *testing.T) {
+	dir := writeSpecFiles(t, map[string]string{
+		"openapi.yaml": `
+openapi: 3.0.3
+info:
+  title: Pets
+  version: 1.0.0
+paths:
+  /pets:
+    $ref: paths/pets.yaml
+components:
+  schemas:
+    Error:
+      $ref: schemas/error.yaml
+`,
+		"paths/pets.yaml": `
+get:
+  parameters:
+    - $ref: ../parameters.yaml#/limit
+  responses:
+    "200":
+      description: OK
+      content:
+        application/json:
+          schema:
+            type: array
+            items:
+              $ref: ../schemas/pet.yaml
+    default:
+      $ref: "../responses.json#/components/responses/Problem"
+`,
+		"schemas/pet.yaml": `
+type: object
+properties:
+  parent:
+    $ref: pet.yaml
+  owner:
+    $ref: "#/$defs/Owner"
+$defs:
+  Owner:
+    type: string
+`,
+		"schemas/error.yaml": "type: object\nproperties:\n  message:\n    type: string\n",
+		"parameters.yaml":    "limit:\n  name: limit\n  in: query\n  schema:\n    type: integer\n",
+		"responses.json":     `{"components": {"responses": {"Problem": {"description": "Problem", "content": {"application/json": {"schema": {"$ref": "openapi.yaml#/components/schemas/Error"}}}}}}}`,
+	})
+
+	content, _, err := loadSpecFromFile(context.Background(), filepath.Join(dir, "openapi.yaml"))
+	require.NoError(t, err)
+
+	var spec map[string]any
+	require.NoError(t, json.Unmarshal([]byte(content), &spec))
+	lookup := func(pointer string) any {
+		tree, err := decodeTree(content)
+		require.NoError(t, err)
+		value, ok := resolvePointer(tree, pointer)
+		require.True(t, ok, "missing %s", pointer)
+		if str, ok := value.(string); ok {
+			return str
+		}
+		encoded, err := encodeTree(value)
+		require.NoError(t, err)
+		return encoded
+	}
+
+	tests := []struct {
+		name     string
+		pointer  string
+		expected string
+	}{
+		{name: "path item is inlined", pointer: "/paths/~1pets/get/responses/200/description", expected: "OK"},
+		{name: "parameter", pointer: "/paths/~1pets/get/parameters/0/$ref", expected: "#/components/parameters/limit"},
+		{name: "whole file schema", pointer: "/paths/~1pets/get/responses/200/content/application~1json/schema/items/$ref", expected: "#/components/schemas/pet"},
+		{name: "component keeps its name", pointer: "/paths/~1pets/get/responses/default/$ref", expected: "#/components/responses/Problem"},
+		{name: "recursive schema", pointer: "/components/schemas/pet/properties/parent/$ref", expected: "#/components/schemas/pet"},
+		{name: "local ref of a referenced file", pointer: "/components/schemas/pet/properties/owner/$ref", expected: "#/components/schemas/Owner"},
+		{name: "bundled local target", pointer: "/components/schemas/Owner", expected: `{"type":"string"}`},
+		{name: "ref back into the root file", pointer: "/components/responses/Problem/content/application~1json/schema/$ref", expected: "#/components/schemas/Error"},
+		{name: "root component is replaced", pointer: "/components/schemas/Error/type", expected: "object"},
+		{name: "parameter content", pointer: "/components/parameters/limit/in", expected: "query"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, lookup(tt.pointer))
+		})
+	}
+
+	t.Run("no external refs left", func(t *testing.T) {
+		tree, err := decodeTree(content)
+		require.NoError(t, err)
+		require.False(t, hasExternalRefs(tree.(*object)))
+	})
+}
+
+func Test_BundleSpecSwagger(t *testing.T) {
+	dir := writeSpecFiles(t, map[string]string{
+		"swagger.json":     `{"swagger": "2.0", "info": {"title": "Legacy"}, "paths": {"/users": {"get": {"responses": {"200": {"description": "OK", "schema": {"$ref": "definitions.json#/definitions/User"}}}}}}}`,
+		"definitions.json": `{"definitions": {"User": {"type": "object", "properties": {"group": {"$ref": "#/definitions/Group"}}}, "Group": {"type": "string"}}}`,
+	})
+
+	content, _, err := loadSpecFromFile(context.Background(), filepath.Join(dir, "swagger.json"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"swagger": "2.0",
+		"info": {"title": "Legacy"},
+		"paths": {"/users": {"get": {"responses": {"200": {"description": "OK", "schema": {"$ref": "#/definitions/User"}}}}}},
+		"definitions": {
+			"User": {"type": "object", "properties": {"group": {"$ref": "#/definitions/Group"}}},
+			"Group": {"type": "string"}
+		}
+	}`, content)
+}
+
+func Test_BundleSpecErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		files         map[string]string
+		expectedErr   error
+		expectedFiles []string
+	}{
+		{
+			name: "missing files are all reported",
+			files: map[string]string{
+				"openapi.json": `{"openapi": "3.0.0", "paths": {"/a": {"$ref": "paths/a.json"}}, "components": {"schemas": {"B": {"$ref": "missing.json"}}}}`,
+				"paths/a.json": `{"get": {"responses": {"200": {"$ref": "../responses/missing.json"}}}}`,
+			},
+			expectedErr:   ErrDanglingRef,
+			expectedFiles: []string{"paths/a.json", "openapi.json"},
+		},
+		{
+			name: "missing pointer",
+			files: map[string]string{
+				"openapi.json": `{"openapi": "3.0.0", "components": {"schemas": {"A": {"$ref": "schemas.json#/Missing"}}}}`,
+				"schemas.json": `{"Present": {"type": "string"}}`,
+			},
+			expectedErr:   ErrDanglingRef,
+			expectedFiles: []string{"openapi.json"},
+		},
+		{
+			name: "invalid referenced file",
+			files: map[string]string{
+				"openapi.json": `{"openapi": "3.0.0", "components": {"schemas": {"A": {"$ref": "a.yaml"}}}}`,
+				"a.yaml":       "type: [broken\n",
+			},
+			expectedErr:   ErrInvalidSpec,
+			expectedFiles: []string{"openapi.json"},
+		},
+		{
+			name: "schemas only referencing each other",
+			files: map[string]string{
+				"openapi.json": `{"openapi": "3.0.0", "paths": {"/a": {"get": {"responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {"$ref": "a.json"}}}}}}}}}`,
+				"a.json":       `{"$ref": "b.json"}`,
+				"b.json":       `{"$ref": "a.json"}`,
+			},
+			expectedErr:   ErrRefCycle,
+			expectedFiles: []string{"a.json"},
+		},
+		{
+			name: "path item referencing itself",
+			files: map[string]string{
+				"openapi.json": `{"openapi": "3.0.0", "paths": {"/a": {"$ref": "a.json"}}}`,
+				"a.json":       `{"$ref": "a.json"}`,
+			},
+			expectedErr:   ErrRefCycle,
+			expectedFiles: []string{"a.json"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeSpecFiles(t, tt.files)
+
+			content, _, err := loadSpecFromFile(context.Background(), filepath.Join(dir, "openapi.json"))
+			require.Error(t, err)
+			require.Empty(t, content)
+			require.ErrorIs(t, err, tt.expectedErr)
+
+			var files []string
+			for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var refErr *RefError
+				require.True(t, errors.As(err, &refErr))
+				files = append(files, filepath.ToSlash(refErr.File[len(dir)+1:]))
+				require.Contains(t, err.Error(), refErr.File)
+			}
+			require.Equal(t, tt.expectedFiles, files)
+		})
+	}
+}
+
+func Test_ResolvePointer(t *testing.T) {
+	doc, err := decodeTree(`{"a": {"b/c": [1, {"d~e": "found"}]}}`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		pointer  string
+		expected any
+		found    bool
+	}{
+		{name: "whole document", pointer: "", expected: doc, found: true},
+		{name: "escaped tokens", pointer: "/a/b~1c/1/d~0e", expected: "found", found: true},
+		{name: "missing key", pointer: "/a/missing", found: false},
+		{name: "index out of range", pointer: "/a/b~1c/2", found: false},
+		{name: "not a pointer", pointer: "a", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, found := resolvePointer(doc, tt.pointer)
+			require.Equal(t, tt.found, found)
+			if tt.found {
+				require.Equal(t, tt.expected, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package goscalar
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// Cache settings of the page and the spec, which change on reloads
+	revalidateCacheControl = "no-cache"
+
+	// standalonePage is the base of the page written by RenderDocs, which inlines
+	// the bundle since nothing serves the asset next to it
+	standalonePage = "standalone"
+
+	// maxCachedVariants bounds the rewritten specs of a snapshot and the pages of
+	// each, rendered for distinct request servers and external bases
+	maxCachedVariants = 16
+)
+
+var (
+	// Errors
+	ErrInvalidCacheMaxAge = errors.New("cache max-age cannot be negative")
+)
+
+// WithCacheMaxAge lets clients reuse the page and the spec for maxAge without
+// revalidating them. By default they revalidate every request, which costs a
+// 304 Not Modified response as long as the spec is unchanged. Reloaded specs
+// may then take up to maxAge to reach clients.
+func WithCacheMaxAge(maxAge time.Duration) Option {
+	return func(s *Scalar) error {
+		if maxAge < 0 {
+			return fmt.Errorf("%w: %s", ErrInvalidCacheMaxAge, maxAge)
+		}
+		s.config.CacheMaxAge = maxAge
+		return nil
+	}
+}
+
+// CacheMaxAge sets how long clients reuse the page and the spec
+func (b *Builder) CacheMaxAge(maxAge time.Duration) *Builder {
+	b.options = append(b.options, WithCacheMaxAge(maxAge))
+	return b
+}
+
+// cacheControl returns the Cache-Control header of the page and the spec
+func (c *Config) cacheControl() string {
+	seconds := int64(c.CacheMaxAge / time.Second)
+	if seconds <= 0 {
+		return revalidateCacheControl
+	}
+	return fmt.Sprintf("max-age=%d", seconds)
+}
+
+// responseCache holds the responses of a configuration snapshot. They are built on
+// first use and dropped when a reload swaps in a new snapshot.
+type responseCache struct {
+	config  *Config
+	modTime time.Time // Last-Modified of the responses, when the snapshot was first served
+
+	// Servers and bases come from the configuration, the Host header or trusted proxies,
+	// so the least recently used variants are evicted when requests name many of them
+	variants *lruCache[*responseVariant] // Keyed by the server the spec is rewritten for
+}
+
+// responseVariant holds the responses of the snapshot with the servers rewritten
+// for a request server, or as loaded
+type responseVariant struct {
+	config *Config
+	pages  *lruCache[*asset] // Keyed by the base the bundle is linked from
+	specs  *lruCache[*asset] // Keyed by the path relative to the mount prefix, only existing ones
+}
+
+// responses returns the response cache of the configuration being served
+func (s *Scalar) responses() *responseCache {
+	config := s.snapshot()
+	if cache := s.cache.Load(); cache != nil && cache.config == config {
+		return cache
+	}
+
+	cache := &responseCache{
+		config:   config,
+		modTime:  time.Now(),
+		variants: newLRUCache[*responseVariant](maxCachedVariants),
+	}
+	s.cache.Store(cache)
+	return cache
+}
+
+// variant returns the responses for the server of a request
+func (c *responseCache) variant(target serverTarget) (*responseVariant, error) {
+	return c.variants.get(target.key, func() (*responseVariant, error) {
+		config := c.config
+		if target.key != "" {
+			var err error
+			if config, err = c.config.withServers(target); err != nil {
+				return nil, err
+			}
+		}
+		return &responseVariant{
+			config: config,
+			pages:  newLRUCache[*asset](maxCachedVariants),
+			specs:  newLRUCache[*asset](0),
+		}, nil
+	})
+}
+
+// page returns the documentation page for the server of a request, linking the
+// bundle from base or relatively when it is empty
+func (c *responseCache) page(base string, target serverTarget) (*asset, error) {
+	variant, err := c.variant(target)
+	if err != nil {
+		return nil, err
+	}
+
+	return variant.pages.get(base, func() (*asset, error) {
+		body, err := variant.config.render(base)
+		if err != nil {
+			return nil, err
+		}
+		return c.newAsset("index", ".html", contentTypeHTML, body, target), nil
+	})
+}
+
+// spec returns the spec of the document with the given slug as JSON, in the given
+// layout, or YAML, for the server of a request. It reports false when there is no
+// such document or format.
+func (c *responseCache) spec(slug, file, layout string, target serverTarget) (*asset, bool, error) {
+	if file != specJSONPath && file != specYAMLPath {
+		return nil, false, nil
+	}
+	if file != specJSONPath {
+		layout = ""
+	}
+
+	variant, err := c.variant(target)
+	if err != nil {
+		return nil, true, err
+	}
+	// Unknown slugs are not cached, the keys stay bounded by the documents
+	content, ok := variant.config.document(slug)
+	if !ok {
+		return nil, false, nil
+	}
+
+	spec, err := variant.specs.get(slug+"/"+file+"?"+layout, func() (*asset, error) {
+		if file == specYAMLPath {
+			converted, err := jsonToYAML(content)
+			if err != nil {
+				return nil, err
+			}
+			return c.newAsset("openapi", ".yaml", contentTypeYAML, []byte(converted), target), nil
+		}
+
+		body, err := layoutJSON(content, layout)
+		if err != nil {
+			return nil, err
+		}
+		return c.newAsset("openapi", ".json", contentTypeJSON, body, target), nil
+	})
+	return spec, true, err
+}
+
+// newAsset creates a response of the snapshot. Responses rewritten for a request
+// server may only ever be served once, so they are only compressed when reused.
+func (c *responseCache) newAsset(baseName, extension, contentType string, body []byte, target serverTarget) *asset {
+	a := newAsset(baseName, extension, contentType, c.config.cacheControl(), body)
+	a.modTime = c.modTime
+	a.compressOnReuse = target.key != ""
+	return a
+}
+
+// lruCache holds values built once per key, evicting the least recently used
+// beyond its limit
+type lruCache[V any] struct {
+	limit int // Zero keeps every value
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Most recently used first
+}
+
+// lruEntry is a value of an lruCache, built on first use
+type lruEntry[V any] struct {
+	key   string
+	value func() (V, error)
+}
+
+// newLRUCache creates a cache holding at most limit values, or any number when it is zero
+func newLRUCache[V any](limit int) *lruCache[V] {
+	return &lruCache[V]{limit: limit, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+// get returns the value of a key, building it on first use. The value is built
+// without holding the lock, once, concurrent callers waiting for it.
+func (l *lruCache[V]) get(key string, build func() (V, error)) (V, error) {
+	l.mu.Lock()
+	element, ok := l.entries[key]
+	if ok {
+		l.order.MoveToFront(element)
+	} else {
+		element = l.order.PushFront(&lruEntry[V]{key: key, value: sync.OnceValues(build)})
+		l.entries[key] = element
+		if l.limit > 0 && l.order.Len() > l.limit {
+			oldest := l.order.Remove(l.order.Back()).(*lruEntry[V])
+			delete(l.entries, oldest.key)
+		}
+	}
+	value := element.Value.(*lruEntry[V]).value
+	l.mu.Unlock()
+
+	return value()
+}
+
+// len returns the number of values held
+func (l *lruCache[V]) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+// notModified evaluates the conditional headers of a request against a response,
+// If-None-Match takes precedence over If-Modified-Since as required by RFC 9110
+func notModified(r *http.Request, etag string, modTime time.Time) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etagMatches(ifNoneMatch, etag)
+	}
+
+	if modTime.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// HTTP dates have a one second resolution
+	return !modTime.Truncate(time.Second).After(since)
+}
@@ -0,0 +1,32 @@
+// Package chiscalar serves goscalar documentation from a chi router
+package chiscalar
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/JhonatanRSantos/goscalar"
+)
+
+// Mount serves the documentation page, the raw spec and the Scalar bundle under the
+// pattern, e.g. Mount(r, "/docs", scalar) serves "/docs/", "/docs/openapi.json" and
+// "/docs/assets/", redirecting "/docs" to "/docs/". The middlewares of the router
+// apply, and middlewares are added inline to the documentation only. Routers
+// mounted under a prefix and patterns with URL parameters are supported.
+func Mount(router chi.Router, pattern string, s *goscalar.Scalar, middlewares ...func(http.Handler) http.Handler) {
+	if len(middlewares) > 0 {
+		router = router.With(middlewares...)
+	}
+
+	router.Mount(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The mount point is the request path without the path left for the mounted handler,
+		// which is "/" for both "/docs" and "/docs/"
+		prefix := r.URL.Path
+		if routePath := chi.RouteContext(r.Context()).RoutePath; routePath != "/" {
+			prefix = strings.TrimSuffix(prefix, routePath)
+		}
+		s.Handler(prefix).ServeHTTP(w, r)
+	}))
+}
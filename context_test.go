@@ -0,0 +1,156 @@
+package goscalar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewScalarContext(t *testing.T) {
+	// The server only answers once the client gives up
+	blocking := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer blocking.Close()
+
+	dir := writeSpecFiles(t, map[string]string{
+		"openapi.json": `{"openapi": "3.0.0", "components": {"schemas": {"Pet": {"$ref": "pet.json"}}}}`,
+		"pet.json":     `{"type": "object"}`,
+	})
+	fsys := fstest.MapFS{"openapi.json": {Data: []byte(`{"openapi": "3.0.0"}`)}}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name        string
+		ctx         func() (context.Context, context.CancelFunc)
+		options     []Option
+		expectedErr error
+	}{
+		{
+			name: "deadline stops a fetch",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+			options:     []Option{WithURL(blocking.URL)},
+			expectedErr: context.DeadlineExceeded,
+		},
+		{
+			name: "cancellation stops a fetch",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			options:     []Option{WithURL(blocking.URL)},
+			expectedErr: context.Canceled,
+		},
+		{
+			name: "refreshing does not hide a cancellation",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return canceled, func() {}
+			},
+			options:     []Option{WithURL(blocking.URL), WithRefresh(time.Minute)},
+			expectedErr: context.Canceled,
+		},
+		{
+			name: "file",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return canceled, func() {}
+			},
+			options:     []Option{WithFile(filepath.Join(dir, "openapi.json"))},
+			expectedErr: context.Canceled,
+		},
+		{
+			name: "file system",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return canceled, func() {}
+			},
+			options:     []Option{WithFS(fsys, "openapi.json")},
+			expectedErr: context.Canceled,
+		},
+		{
+			name: "source",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return canceled, func() {}
+			},
+			options:     []Option{WithSource("Pets", "pets", WithFile(filepath.Join(dir, "openapi.json")))},
+			expectedErr: context.Canceled,
+		},
+		{
+			name: "in-memory specs ignore the context",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return canceled, func() {}
+			},
+			options: []Option{WithSpecContent(`{"openapi": "3.0.0"}`)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			start := time.Now()
+			scalar, err := NewScalarContext(ctx, tt.options...)
+			if tt.expectedErr == nil {
+				require.NoError(t, err)
+				scalar.Close()
+				return
+			}
+
+			require.ErrorIs(t, err, tt.expectedErr)
+			require.Nil(t, scalar)
+			require.Less(t, time.Since(start), 5*time.Second)
+		})
+	}
+
+	t.Run("builder", func(t *testing.T) {
+		_, err := NewBuilder().File(filepath.Join(dir, "openapi.json")).BuildContext(canceled)
+		require.ErrorIs(t, err, context.Canceled)
+
+		scalar, err := NewBuilder().File(filepath.Join(dir, "openapi.json")).BuildContext(context.Background())
+		require.NoError(t, err)
+		defer scalar.Close()
+		require.Contains(t, serveSpec(t, scalar), `"Pet":{"type":"object"}`)
+	})
+}
+
+func Test_FetchWithoutClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"openapi": "3.0.0", "info": {"title": "No Timeout"}}`))
+	}))
+	defer server.Close()
+
+	// A client without Timeout used to get an already expired context
+	scalar, err := NewScalar(WithHTTPClient(&http.Client{}), WithURL(server.URL))
+	require.NoError(t, err)
+	defer scalar.Close()
+	require.Contains(t, serveSpec(t, scalar), "No Timeout")
+}
+
+func Test_BundleSpecContext(t *testing.T) {
+	dir := writeSpecFiles(t, map[string]string{
+		"openapi.json": `{"openapi": "3.0.0", "components": {"schemas": {"Pet": {"$ref": "pet.json"}}}}`,
+		"pet.json":     `{"type": "object"}`,
+	})
+	root, err := os.ReadFile(filepath.Join(dir, "openapi.json"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The root file is already read, referenced files are not
+	content, err := bundleSpec(ctx, osFiles{}, filepath.Join(dir, "openapi.json"), root)
+	require.Empty(t, content)
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotErrorIs(t, err, ErrDanglingRef)
+}
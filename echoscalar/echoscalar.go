@@ -0,0 +1,31 @@
+// Package echoscalar serves goscalar documentation from an Echo router
+package echoscalar
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/JhonatanRSantos/goscalar"
+)
+
+// methods answered by the documentation routes
+var methods = []string{http.MethodGet, http.MethodHead}
+
+// Mount serves the documentation page, the raw spec and the Scalar bundle under the
+// group, e.g. Mount(e.Group("/docs"), scalar) serves "/docs/", "/docs/openapi.json"
+// and "/docs/assets/", redirecting "/docs" to "/docs/". The middleware of the group
+// applies, and middleware is added to the documentation routes only. The group
+// prefix may contain parameters.
+func Mount(group *echo.Group, s *goscalar.Scalar, middleware ...echo.MiddlewareFunc) {
+	handler := func(c echo.Context) error {
+		// The mount point is the request path without what the wildcard matched
+		prefix := strings.TrimSuffix(c.Request().URL.Path, c.Param("*"))
+		s.Handler(prefix).ServeHTTP(c.Response(), c.Request())
+		return nil
+	}
+
+	group.Match(methods, "", handler, middleware...)
+	group.Match(methods, "/*", handler, middleware...)
+}
@@ -0,0 +1,96 @@
+package goscalar
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// snippetSize caps the snippet of a LoadError, in bytes
+const snippetSize = 256
+
+// LoadError describes a spec that could not be loaded, use errors.As to get it.
+// It wraps the underlying error, so errors.Is still matches the package errors.
+type LoadError struct {
+	Kind       string // Kind of the source, one of the SourceKind constants
+	Location   string // Path or redacted URL of the source
+	StatusCode int    // HTTP status of URL sources, zero when no response was received
+	Snippet    string // Start of a rejected response body, or the line of a parse error
+	Line       int    // Line of a parse error, zero otherwise
+	Column     int    // Column of a parse error, zero otherwise
+	Err        error
+}
+
+// Error implements the error interface. The snippet is left out, since response
+// bodies do not belong in logs.
+func (e *LoadError) Error() string {
+	if e.Location == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", e.Location, e.Err)
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// newLoadError describes an error of a source. data is the content the source
+// returned, if any, and gives the snippet.
+func newLoadError(kind, location string, data []byte, err error) *LoadError {
+	loadErr := &LoadError{Kind: kind, Location: location, Err: err}
+
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		loadErr.Line = parseErr.Line
+		loadErr.Column = parseErr.Column
+	}
+	if data != nil {
+		loadErr.Snippet = snippet(string(data), loadErr.Line)
+	}
+	return loadErr
+}
+
+// statusError is returned for responses with a non-2xx status
+type statusError struct {
+	code    int
+	status  string
+	snippet string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%v: HTTP %s", ErrHTTPRequest, e.status)
+}
+
+func (e *statusError) Unwrap() error {
+	return ErrHTTPRequest
+}
+
+// snippet returns a line of content, or its start when line is zero. Lines are
+// counted like ParseError does, ignoring leading blank lines.
+func snippet(content string, line int) string {
+	content = strings.TrimSpace(content)
+	if line > 0 {
+		lines := strings.Split(content, "\n")
+		if line <= len(lines) {
+			content = strings.TrimSpace(lines[line-1])
+		}
+	}
+
+	if len(content) <= snippetSize {
+		return content
+	}
+	cut := snippetSize
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	return content[:cut]
+}
+
+// joinErrors combines the errors of several options, a single error is returned as is
+func joinErrors(errs []error) error {
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,180 @@
+package goscalar
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LoadError(t *testing.T) {
+	long := strings.Repeat("é", snippetSize)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("  no spec here\n"))
+		case "/long":
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(long))
+		case "/invalid":
+			w.Write([]byte("openapi: 3.0.0\ninfo:\n\ttitle: Broken\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	invalidFile := filepath.Join(dir, "openapi.json")
+	require.NoError(t, os.WriteFile(invalidFile, []byte("{\n  \"openapi\": \"3.0.0\",\n  \"info\": ]\n}"), 0644))
+
+	credentialsURL := strings.Replace(server.URL, "http://", "http://user:secret@", 1)
+
+	tests := []struct {
+		name     string
+		option   Option
+		expected LoadError
+		message  string
+	}{
+		{
+			name:     "HTTP status",
+			option:   WithURL(credentialsURL + "/missing"),
+			expected: LoadError{Kind: SourceKindURL, Location: strings.Replace(credentialsURL, "secret", "xxxxx", 1) + "/missing", StatusCode: http.StatusNotFound, Snippet: "no spec here"},
+			message:  "HTTP request failed: HTTP 404 Not Found",
+		},
+		{
+			name:     "long response",
+			option:   WithURL(server.URL + "/long"),
+			expected: LoadError{Kind: SourceKindURL, Location: server.URL + "/long", StatusCode: http.StatusBadGateway, Snippet: long[:snippetSize]},
+			message:  "HTTP 502 Bad Gateway",
+		},
+		{
+			name:     "invalid response",
+			option:   WithURL(server.URL + "/invalid"),
+			expected: LoadError{Kind: SourceKindURL, Location: server.URL + "/invalid", StatusCode: http.StatusOK, Snippet: "title: Broken", Line: 3},
+			message:  "invalid YAML spec",
+		},
+		{
+			name:     "invalid file",
+			option:   WithFile(invalidFile),
+			expected: LoadError{Kind: SourceKindFile, Location: invalidFile, Line: 3, Column: 11},
+			message:  "invalid JSON spec at line 3, column 11",
+		},
+		{
+			name:     "missing file system entry",
+			option:   WithFS(fstest.MapFS{}, "openapi.yaml"),
+			expected: LoadError{Kind: SourceKindFS, Location: "openapi.yaml"},
+			message:  "openapi.yaml: failed to read file",
+		},
+		{
+			name:     "invalid content",
+			option:   WithSpecContent("{\"openapi\": ]}"),
+			expected: LoadError{Kind: SourceKindContent, Snippet: "{\"openapi\": ]}", Line: 1, Column: 13},
+			message:  "invalid JSON spec at line 1, column 13",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewScalar(tt.option)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.message)
+			require.NotContains(t, err.Error(), "secret")
+
+			var loadErr *LoadError
+			require.True(t, errors.As(err, &loadErr))
+			require.Equal(t, tt.expected.Kind, loadErr.Kind)
+			require.Equal(t, tt.expected.Location, loadErr.Location)
+			require.Equal(t, tt.expected.StatusCode, loadErr.StatusCode)
+			require.Equal(t, tt.expected.Snippet, loadErr.Snippet)
+			require.Equal(t, tt.expected.Line, loadErr.Line)
+			if tt.expected.Column > 0 {
+				require.Equal(t, tt.expected.Column, loadErr.Column)
+			}
+		})
+	}
+
+	t.Run("status is not repeated", func(t *testing.T) {
+		_, err := NewScalar(WithURL(server.URL + "/missing"))
+		require.Equal(t, 1, strings.Count(err.Error(), "404"))
+		require.ErrorIs(t, err, ErrHTTPRequest)
+	})
+
+	t.Run("parse errors", func(t *testing.T) {
+		_, err := NewScalar(WithURL(server.URL + "/invalid"))
+		require.ErrorIs(t, err, ErrInvalidSpec)
+
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr))
+		require.Equal(t, formatYAML, parseErr.Format)
+	})
+
+	t.Run("refresh failures", func(t *testing.T) {
+		scalar, err := NewScalar(WithURL(server.URL+"/missing"), WithRefresh(time.Minute))
+		require.NoError(t, err)
+		defer scalar.Close()
+
+		var loadErr *LoadError
+		require.True(t, errors.As(scalar.RefreshStatus().LastError, &loadErr))
+		require.Equal(t, http.StatusNotFound, loadErr.StatusCode)
+	})
+}
+
+func Test_NewScalarJoinsErrors(t *testing.T) {
+	t.Run("every option failure is reported", func(t *testing.T) {
+		scalar, err := NewScalar(
+			WithTitle(""),
+			WithSpecContent("{\"openapi\": ]}"),
+			WithRefreshBackoff(time.Second, time.Millisecond),
+			WithURL("ftp://example.com/openapi.json"),
+		)
+		require.Nil(t, scalar)
+		require.ErrorIs(t, err, ErrInvalidTitle)
+		require.ErrorIs(t, err, ErrInvalidSpec)
+		require.ErrorIs(t, err, ErrInvalidRefresh)
+		require.ErrorIs(t, err, ErrUnsupportedScheme)
+		require.NotErrorIs(t, err, ErrSpecRequired)
+		require.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 4)
+	})
+
+	t.Run("a single failure is returned as is", func(t *testing.T) {
+		_, err := NewScalar(WithTitle(""), WithSpecContent(`{"openapi": "3.0.0"}`))
+		require.Equal(t, ErrInvalidTitle, err)
+	})
+
+	t.Run("fetch failures are joined", func(t *testing.T) {
+		_, err := NewScalar(WithTitle(""), WithURL("http://127.0.0.1:1/openapi.json"))
+		require.ErrorIs(t, err, ErrInvalidTitle)
+		require.ErrorIs(t, err, ErrHTTPRequest)
+	})
+}
+
+func Test_Snippet(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		line     int
+		expected string
+	}{
+		{name: "start of content", content: "\n\n  body  \n", expected: "body"},
+		{name: "line", content: "\na: 1\nb: [\n", line: 2, expected: "b: ["},
+		{name: "line out of range", content: "a: 1", line: 5, expected: "a: 1"},
+		{name: "long content", content: strings.Repeat("x", snippetSize+10), expected: strings.Repeat("x", snippetSize)},
+		{name: "multi-byte rune at the cut", content: "x" + strings.Repeat("é", snippetSize), expected: "x" + strings.Repeat("é", (snippetSize-1)/2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, snippet(tt.content, tt.line))
+		})
+	}
+}
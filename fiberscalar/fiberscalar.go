@@ -0,0 +1,29 @@
+// Package fiberscalar serves goscalar documentation from a Fiber router
+package fiberscalar
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+
+	"github.com/JhonatanRSantos/goscalar"
+)
+
+// Mount serves the documentation page, the raw spec and the Scalar bundle under the
+// router, e.g. Mount(app.Group("/docs"), scalar) serves "/docs/", "/docs/openapi.json"
+// and "/docs/assets/", redirecting "/docs" to "/docs/". The middleware of the group
+// applies, and handlers run before the documentation like any other handler chain.
+// The group prefix may contain parameters.
+func Mount(router fiber.Router, s *goscalar.Scalar, handlers ...fiber.Handler) {
+	chain := append(slices.Clone(handlers), func(c *fiber.Ctx) error {
+		// The mount point is the request path without what the wildcard matched
+		prefix := strings.TrimSuffix(c.Path(), c.Params("*"))
+		return adaptor.HTTPHandler(s.Handler(prefix))(c)
+	})
+
+	// Fiber answers HEAD requests with the GET routes
+	router.Get("", chain...)
+	router.Get("/*", chain...)
+}
@@ -0,0 +1,104 @@
+package fiberscalar
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/JhonatanRSantos/goscalar"
+)
+
+const validContent = `{"openapi": "3.0.0", "info": {"title": "Fiber API", "version": "1.0.0"}}`
+
+// header returns a handler setting a response header
+func header(key, value string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(key, value)
+		return c.Next()
+	}
+}
+
+func Test_Mount(t *testing.T) {
+	scalar, err := goscalar.NewScalar(goscalar.WithTitle("Fiber Docs"), goscalar.WithSpecContent(validContent))
+	require.NoError(t, err)
+
+	app := fiber.New()
+	Mount(app.Group("/docs"), scalar)
+
+	api := app.Group("/api/:version", header("X-Group", "api"))
+	Mount(api.Group("/docs"), scalar, header("X-Handler", "docs"))
+
+	app.Get("/health", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	assetPath := scriptPath(t, app)
+
+	tests := []struct {
+		name             string
+		method           string
+		target           string
+		expectedStatus   int
+		expectedLocation string
+		expectedBody     string
+		expectedHeaders  map[string]string
+	}{
+		{name: "page", method: http.MethodGet, target: "/docs/", expectedStatus: http.StatusOK, expectedBody: "Fiber Docs"},
+		{name: "bare prefix", method: http.MethodGet, target: "/docs", expectedStatus: http.StatusMovedPermanently, expectedLocation: "/docs/"},
+		{name: "spec", method: http.MethodGet, target: "/docs/openapi.json", expectedStatus: http.StatusOK, expectedBody: validContent},
+		{name: "YAML spec", method: http.MethodGet, target: "/docs/openapi.yaml", expectedStatus: http.StatusOK, expectedBody: "title: Fiber API"},
+		{name: "asset", method: http.MethodGet, target: "/docs/" + assetPath, expectedStatus: http.StatusOK},
+		{name: "HEAD", method: http.MethodHead, target: "/docs/openapi.json", expectedStatus: http.StatusOK},
+		{name: "unknown file", method: http.MethodGet, target: "/docs/missing.json", expectedStatus: http.StatusNotFound},
+		{
+			name:            "nested group with middleware",
+			method:          http.MethodGet,
+			target:          "/api/v1/docs/openapi.json",
+			expectedStatus:  http.StatusOK,
+			expectedBody:    validContent,
+			expectedHeaders: map[string]string{"X-Group": "api", "X-Handler": "docs"},
+		},
+		{name: "nested bare prefix", method: http.MethodGet, target: "/api/v2/docs", expectedStatus: http.StatusMovedPermanently, expectedLocation: "/api/v2/docs/"},
+		{name: "other routes", method: http.MethodGet, target: "/health", expectedStatus: http.StatusOK, expectedBody: "ok"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(tt.method, tt.target, nil))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+			require.Equal(t, tt.expectedLocation, resp.Header.Get("Location"))
+			require.Contains(t, string(body), tt.expectedBody)
+			for key, value := range tt.expectedHeaders {
+				require.Equal(t, value, resp.Header.Get(key))
+			}
+		})
+	}
+}
+
+// scriptPath returns the path of the Scalar bundle referenced by the page, relative to it
+func scriptPath(t *testing.T, app *fiber.App) string {
+	t.Helper()
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/docs/", nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	_, rest, ok := strings.Cut(string(body), `<script src="`)
+	require.True(t, ok)
+	path, _, _ := strings.Cut(rest, `"`)
+	return path
+}
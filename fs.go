@@ -0,0 +1,56 @@
+package goscalar
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+)
+
+// WithFS loads a JSON or YAML specification from a file system, such as an embed.FS.
+// The path is slash-separated and relative to the file system root. Relative $refs
+// are bundled like with WithFile, as long as they stay inside the file system.
+func WithFS(fsys fs.FS, name string) Option {
+	return func(s *Scalar) error {
+		if err := s.loadFrom(FSSource{FS: fsys, Name: name}); err != nil {
+			return fmt.Errorf("failed to load spec from fs: %w", err)
+		}
+		return nil
+	}
+}
+
+// FS loads specification from a file system
+func (b *Builder) FS(fsys fs.FS, name string) *Builder {
+	b.options = append(b.options, WithFS(fsys, name))
+	return b
+}
+
+// FromFS creates a Scalar instance from a file system
+func FromFS(fsys fs.FS, name string, options ...Option) (*Scalar, error) {
+	opts := append([]Option{WithFS(fsys, name)}, options...)
+	return NewScalar(opts...)
+}
+
+// loadSpecFromFS loads specification content from a file system
+func loadSpecFromFS(ctx context.Context, fsys fs.FS, name string) (string, error) {
+	if fsys == nil {
+		return "", fmt.Errorf("%w: nil file system", fs.ErrInvalid)
+	}
+
+	name = path.Clean(filepath.ToSlash(name))
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return bundleSpec(ctx, fsFiles{fsys: fsys}, name, content)
+}
@@ -0,0 +1,120 @@
+package goscalar
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"docs/openapi.yaml":      {Data: []byte("openapi: 3.0.0\ninfo:\n  title: Embedded API\n  version: 1.0.0\npaths:\n  /pets:\n    $ref: paths/pets.yaml\n")},
+		"docs/paths/pets.yaml":   {Data: []byte("get:\n  responses:\n    '200':\n      description: OK\n      content:\n        application/json:\n          schema:\n            $ref: ../schemas/pet.json\n")},
+		"docs/schemas/pet.json":  {Data: []byte(`{"type": "object"}`)},
+		"docs/dangling.yaml":     {Data: []byte("openapi: 3.0.0\ncomponents:\n  schemas:\n    Pet:\n      $ref: schemas/missing.yaml\n")},
+		"docs/escaping.yaml":     {Data: []byte("openapi: 3.0.0\ncomponents:\n  schemas:\n    Pet:\n      $ref: ../../secret.yaml\n")},
+		"docs/invalid.json":      {Data: []byte(`{"openapi": `)},
+		"docs/openapi-flat.json": {Data: []byte(`{"openapi": "3.0.0", "info": {"title": "Flat", "version": "1"}}`)},
+	}
+
+	tests := []struct {
+		name            string
+		fsys            fs.FS
+		path            string
+		expectedContent string
+		expectedErr     error
+		expectedPath    string
+	}{
+		{
+			name:            "bundled spec",
+			fsys:            fsys,
+			path:            "docs/openapi.yaml",
+			expectedContent: `"$ref":"#/components/schemas/pet"`,
+		},
+		{
+			name:            "leading dot",
+			fsys:            fsys,
+			path:            "./docs/openapi-flat.json",
+			expectedContent: `"title": "Flat"`,
+		},
+		{
+			name:         "missing file",
+			fsys:         fsys,
+			path:         "docs/missing.yaml",
+			expectedErr:  fs.ErrNotExist,
+			expectedPath: "docs/missing.yaml",
+		},
+		{
+			name:         "absolute path",
+			fsys:         fsys,
+			path:         "/docs/openapi.yaml",
+			expectedErr:  fs.ErrInvalid,
+			expectedPath: "/docs/openapi.yaml",
+		},
+		{
+			name:         "dangling reference",
+			fsys:         fsys,
+			path:         "docs/dangling.yaml",
+			expectedErr:  ErrDanglingRef,
+			expectedPath: "docs/dangling.yaml",
+		},
+		{
+			name:         "reference outside of the file system",
+			fsys:         fsys,
+			path:         "docs/escaping.yaml",
+			expectedErr:  ErrDanglingRef,
+			expectedPath: "docs/escaping.yaml",
+		},
+		{
+			name:        "invalid spec",
+			fsys:        fsys,
+			path:        "docs/invalid.json",
+			expectedErr: ErrInvalidSpec,
+		},
+		{
+			name:        "nil file system",
+			path:        "docs/openapi.yaml",
+			expectedErr: fs.ErrInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scalar := &Scalar{}
+			err := WithFS(tt.fsys, tt.path)(scalar)
+
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				if tt.expectedPath != "" {
+					require.Contains(t, err.Error(), tt.expectedPath)
+				}
+
+				var refErr *RefError
+				if errors.As(err, &refErr) {
+					require.Equal(t, tt.expectedPath, refErr.File)
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			require.Contains(t, scalar.config.Content, tt.expectedContent)
+		})
+	}
+}
+
+func Test_FromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"openapi.json": {Data: []byte(`{"openapi": "3.0.0", "info": {"title": "Embedded API", "version": "1.0.0"}}`)},
+	}
+
+	scalar, err := FromFS(fsys, "openapi.json", WithTitle("Embedded"))
+	require.NoError(t, err)
+	require.Contains(t, serveSpec(t, scalar), "Embedded API")
+
+	scalar, err = NewBuilder().FS(fsys, "openapi.json").Build()
+	require.NoError(t, err)
+	require.Contains(t, scalar.config.Content, "Embedded API")
+}
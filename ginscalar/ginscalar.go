@@ -0,0 +1,33 @@
+// Package ginscalar serves goscalar documentation from a Gin router
+package ginscalar
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JhonatanRSantos/goscalar"
+)
+
+// wildcard names the catch-all parameter of the documentation routes
+const wildcard = "filepath"
+
+// Mount serves the documentation page, the raw spec and the Scalar bundle under the
+// group, e.g. Mount(router.Group("/docs"), scalar) serves "/docs/", "/docs/openapi.json"
+// and "/docs/assets/", redirecting "/docs" to "/docs/". The middleware of the group
+// applies, and handlers run before the documentation like any other handler chain.
+// The group path may contain parameters.
+func Mount(group *gin.RouterGroup, s *goscalar.Scalar, handlers ...gin.HandlerFunc) {
+	chain := append(slices.Clone(handlers), func(c *gin.Context) {
+		// The mount point is the request path without what the wildcard matched
+		prefix := strings.TrimSuffix(c.Request.URL.Path, c.Param(wildcard))
+		s.Handler(prefix).ServeHTTP(c.Writer, c.Request)
+	})
+
+	for _, path := range []string{"", "/*" + wildcard} {
+		group.Handle(http.MethodGet, path, chain...)
+		group.Handle(http.MethodHead, path, chain...)
+	}
+}
@@ -1,6 +1,8 @@
 package goscalar
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"embed"
 	"encoding/json"
@@ -9,10 +11,14 @@ import (
 	"html/template"
 	"io"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/swaggo/swag"
@@ -32,8 +38,12 @@ const (
 	httpsScheme = "https"
 	filePrefix  = "file://"
 
+	// pageOverhead approximates the size of the page template around the spec and script
+	pageOverhead = 4 << 10
+
 	// HTTP client settings
-	defaultTimeout = 30 * time.Second
+	defaultTimeout   = 30 * time.Second
+	defaultUserAgent = "go-scalar/1.0"
 )
 
 var (
@@ -42,28 +52,99 @@ var (
 	//go:embed templates/*.html
 	embedTemplates embed.FS
 
+	// pageTemplate parses the page template once per process
+	pageTemplate = sync.OnceValues(func() (*template.Template, error) {
+		return utils.ParseTemplateFromFS(embedTemplates, templatePattern)
+	})
+
 	// Errors
-	ErrInvalidTitle      = errors.New("title cannot be empty")
-	ErrInvalidSpec       = errors.New("spec cannot be empty")
-	ErrInvalidURL        = errors.New("invalid URL provided")
-	ErrSpecRequired      = errors.New("spec content is required, use WithFile(), WithURL(), or WithSpec()")
-	ErrUnsupportedScheme = errors.New("unsupported URL scheme, only file://, http://, and https:// are supported")
-	ErrHTTPRequest       = errors.New("HTTP request failed")
-	ErrEmptyResponse     = errors.New("received empty response from URL")
+	ErrInvalidTitle       = errors.New("title cannot be empty")
+	ErrInvalidSpec        = errors.New("spec cannot be empty")
+	ErrInvalidURL         = errors.New("invalid URL provided")
+	ErrSpecRequired       = errors.New("spec content is required, use WithFile(), WithURL(), or WithSpec()")
+	ErrUnsupportedScheme  = errors.New("unsupported URL scheme, only file://, http://, and https:// are supported")
+	ErrHTTPRequest        = errors.New("HTTP request failed")
+	ErrEmptyResponse      = errors.New("received empty response from URL")
+	ErrWatchRequiresFile  = errors.New("watching requires a spec loaded with WithFile()")
+	ErrRefreshRequiresURL = errors.New("refreshing requires a spec loaded with WithURL()")
+	ErrInvalidRefresh     = errors.New("refresh interval and backoff must be positive")
 )
 
 // Scalar represents the API documentation generator
 type Scalar struct {
-	config Config
+	config  Config
+	current atomic.Pointer[Config] // Config being served, swapped atomically on reloads
+
+	// File watching
+	filePath   string   // Absolute path of the spec loaded with WithFile
+	watchFiles []string // Files the WithFile spec was read from, including bundled $refs
+	watch      bool
+	interval   time.Duration // Polling interval used when file system events are unavailable
+	onReload   func(ReloadEvent)
+
+	// URL loading and refreshing
+	specURL         string        // URL of the spec loaded with WithURL
+	urlPending      bool          // The WithURL spec is fetched once every option is applied
+	urlHeader       http.Header   // Headers sent with every URL request
+	urlHeaderFunc   HeaderFunc    // Sets per-request headers, e.g. rotating tokens
+	userAgent       string        // User-Agent of URL requests
+	fetchPolicy     *FetchPolicy  // Restricts what URL requests may reach
+	verification    verification  // Digest and signature the WithURL spec must match
+	refreshInterval time.Duration // Zero disables refreshing
+	minBackoff      time.Duration // First retry delay after a failed refresh
+	maxBackoff      time.Duration // Retry delay cap, defaults to the refresh interval
+	refreshMu       sync.Mutex
+	refreshStatus   RefreshStatus
+
+	// Sources and merging
+	pendingSources []pendingSource // WithSource documents loaded once every option is applied
+	mergeServices  []MergeService  // Services of WithMerge
+	mergePending   bool            // The WithMerge services are loaded once every option is applied
+	mergeWarnings  []MergeWarning  // Conflicts WithMerge could not resolve
+
+	sourceMetadata SourceMetadata  // Origin of the spec loaded at construction
+	loadCtx        context.Context // Context of NewScalarContext, only set while options run
+
+	// Reconfiguration
+	options  []Option     // Options of the served configuration, applied again by Reload
+	updateMu sync.Mutex   // Serializes Update, Reload and Close
+	stateMu  sync.RWMutex // Guards the metadata replaced by Update and Reload
+	closed   bool
+
+	cache atomic.Pointer[responseCache] // Responses of the current snapshot, built on first use
+
+	// Background workers
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
 }
 
 // Config holds the template configuration
 type Config struct {
 	Title      string
 	Language   string
-	Script     template.JS
-	Content    string
-	HTTPClient *http.Client // Optional HTTP client for URL requests
+	Script     template.HTML   // Script element loading the Scalar bundle
+	Content    string          // Normalized JSON specification
+	Documents  []Document      // Documents added with WithSource, shown instead of Content
+	Reference  ReferenceConfig // Options passed to Scalar.createApiReference
+	HTTPClient *http.Client    // Optional HTTP client for URL requests
+
+	CacheMaxAge    time.Duration  // How long clients reuse the page and the spec, zero revalidates every request
+	ExternalURL    string         // URL clients reach the mount point at, set with WithExternalURL
+	TrustedProxies []netip.Prefix // Proxies whose forwarded headers are honored
+	ServerRewrite  *ServerRewrite // Rewrites the servers of the spec for each request, set with WithServerRewrite
+}
+
+// pageData holds the values rendered into the page template.
+// JSON values are typed as template.JS so html/template embeds them verbatim
+// inside their application/json script blocks, they must be escaped beforehand.
+type pageData struct {
+	Title     string
+	Language  string
+	Script    template.HTML
+	Reference template.JS
+	Content   template.JS
+	Sources   template.JS // Set instead of Content when the page shows several documents
 }
 
 // Option defines a configuration option for Scalar
@@ -93,28 +174,53 @@ func WithLanguage(language string) Option {
 	}
 }
 
-// WithFile loads specification from a file path
+// WithFile loads a JSON or YAML specification from a file path
 func WithFile(filePath string) Option {
 	return func(s *Scalar) error {
-		content, err := loadSpecFromFile(filePath)
-		if err != nil {
+		if err := s.loadFrom(FileSource{Path: filePath}); err != nil {
 			return fmt.Errorf("failed to load spec from file: %w", err)
 		}
-		s.config.Content = escapeJSString(content)
+		s.filePath = filePath
+		s.watchFiles = s.sourceMetadata.files
 		return nil
 	}
 }
 
-// WithURL loads specification from a URL (HTTP/HTTPS)
+// WithURL loads a JSON or YAML specification from a URL (HTTP/HTTPS)
 func WithURL(specURL string) Option {
 	return func(s *Scalar) error {
-		content, err := loadSpecFromURL(specURL, s.config.HTTPClient)
-		if err != nil {
+		if err := validateURL(specURL); err != nil {
 			return fmt.Errorf("failed to load spec from URL: %w", err)
 		}
-		s.config.Content = escapeJSString(content)
+		s.specURL = specURL
+		s.urlPending = true
+		s.mergePending = false
+		return nil
+	}
+}
+
+// loadURL fetches the WithURL spec. It runs once every option is applied, so
+// WithHTTPClient and the request options apply whatever their order.
+func (s *Scalar) loadURL() error {
+	if !s.urlPending {
 		return nil
 	}
+	s.urlPending = false
+
+	content, err := s.fetchSpecURL(s.loadContext())
+	if err != nil {
+		return fmt.Errorf("failed to load spec from URL: %w", err)
+	}
+	status := s.RefreshStatus()
+	s.config.Content = content
+	s.sourceMetadata = SourceMetadata{
+		Kind:         SourceKindURL,
+		Location:     status.Source,
+		ETag:         status.ETag,
+		LastModified: status.LastModified,
+		LoadedAt:     status.LastSuccess,
+	}
+	return nil
 }
 
 // WithHTTPClient sets a custom HTTP client for URL requests
@@ -131,102 +237,294 @@ func WithHTTPClient(client *http.Client) Option {
 // WithSpec loads specification from swag.Spec object
 func WithSpec(spec *swag.Spec) Option {
 	return func(s *Scalar) error {
-		if spec == nil {
-			return ErrInvalidSpec
-		}
-		content := spec.ReadDoc()
-		if content == "" {
-			return ErrInvalidSpec
-		}
-		s.config.Content = escapeJSString(normalizeSpecContent(content))
-		return nil
+		return s.loadFrom(SwagSource{Spec: spec})
 	}
 }
 
-// WithSpecContent loads specification from raw content
+// WithSpecContent loads specification from raw JSON or YAML content
 func WithSpecContent(content string) Option {
 	return func(s *Scalar) error {
-		content = strings.TrimSpace(content)
-		if content == "" {
-			return ErrInvalidSpec
-		}
-		s.config.Content = escapeJSString(normalizeSpecContent(content))
-		return nil
+		return s.loadFrom(ContentSource(content))
 	}
 }
 
 // NewScalar creates a new Scalar instance with the given options
 func NewScalar(options ...Option) (*Scalar, error) {
+	return NewScalarContext(context.Background(), options...)
+}
+
+// NewScalarContext creates a new Scalar instance, loading specs under ctx.
+// Cancelling ctx stops in-flight fetches and file reads, the returned
+// error then wraps context.Canceled or context.DeadlineExceeded.
+func NewScalarContext(ctx context.Context, options ...Option) (*Scalar, error) {
+	scalar, err := configure(ctx, options, true)
+	if err != nil {
+		return nil, err
+	}
+
+	scalar.options = slices.Clone(options)
+	current := scalar.config
+	scalar.current.Store(&current)
+
+	if err := scalar.start(); err != nil {
+		return nil, err
+	}
+	return scalar, nil
+}
+
+// configure applies options to a new instance and validates the result. When
+// unavailable is set, an unreachable WithURL source is replaced by a placeholder
+// as long as WithRefresh can fetch it later.
+func configure(ctx context.Context, options []Option, unavailable bool) (*Scalar, error) {
 	scalar := &Scalar{
+		loadCtx: ctx,
 		config: Config{
 			Title:      defaultTitle,
 			Language:   defaultLanguage,
-			Script:     template.JS(fmt.Sprintf("<script>%s</script>", embedScript)),
+			Script:     scriptTag(false),
+			Reference:  defaultReferenceConfig(),
 			HTTPClient: &http.Client{Timeout: defaultTimeout},
 		},
 	}
 
+	// Every option is applied, so all their errors are reported together
+	var errs []error
 	for _, opt := range options {
 		if err := opt(scalar); err != nil {
-			return nil, err
+			errs = append(errs, err)
 		}
 	}
 
-	if scalar.config.Content == "" {
+	errs = append(errs, scalar.loadSources()...)
+	if err := scalar.loadMerge(); err != nil {
+		errs = append(errs, err)
+	}
+
+	// An unreachable URL is only fatal without WithRefresh
+	if err := scalar.loadURL(); err != nil {
+		// A cancelled construction is not an unreachable source, and a spec failing
+		// verification is not one to wait for
+		if !unavailable || scalar.refreshInterval <= 0 || ctx.Err() != nil || errors.Is(err, ErrVerificationFailed) {
+			errs = append(errs, err)
+		} else {
+			scalar.config.Content = unavailableSpec(scalar.config.Title)
+		}
+	}
+	scalar.loadCtx = nil
+
+	if len(errs) > 0 {
+		return nil, joinErrors(errs)
+	}
+
+	if scalar.config.Content == "" && len(scalar.config.Documents) == 0 {
 		return nil, ErrSpecRequired
 	}
 
+	if scalar.config.Content != "" && len(scalar.config.Documents) > 0 {
+		return nil, fmt.Errorf("%w: sources cannot be combined with a top-level spec", ErrInvalidSource)
+	}
+
+	if scalar.watch && scalar.filePath == "" {
+		return nil, ErrWatchRequiresFile
+	}
+
+	if scalar.refreshInterval > 0 && scalar.specURL == "" {
+		return nil, ErrRefreshRequiresURL
+	}
+
+	if scalar.verification.enabled() && scalar.specURL == "" {
+		return nil, ErrVerifyRequiresURL
+	}
+
 	return scalar, nil
 }
 
-// RenderDocs renders the API documentation to the provided writer
+// start starts the background workers enabled by WithWatch and WithRefresh
+func (s *Scalar) start() error {
+	if s.watch {
+		if err := s.startWatch(); err != nil {
+			return err
+		}
+	}
+
+	if s.refreshInterval > 0 {
+		s.startRefresh()
+	}
+	return nil
+}
+
+// snapshot returns the configuration currently being served
+func (s *Scalar) snapshot() *Config {
+	if current := s.current.Load(); current != nil {
+		return current
+	}
+	return &s.config
+}
+
+// swapContent atomically replaces the served spec, it reports whether the spec changed
+func (s *Scalar) swapContent(content string) bool {
+	for {
+		current := s.current.Load()
+		if current.Content == content {
+			return false
+		}
+
+		next := *current
+		next.Content = content
+		if s.current.CompareAndSwap(current, &next) {
+			return true
+		}
+	}
+}
+
+// Close stops the background workers started by WithWatch and WithRefresh. It is safe to call more than once.
+func (s *Scalar) Close() error {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+
+	s.closed = true
+	s.stopWorkers()
+	return nil
+}
+
+// stopWorkers stops the background workers and waits for them to return
+func (s *Scalar) stopWorkers() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.workers.Wait()
+	s.ctx, s.cancel = nil, nil
+}
+
+// startWorker runs fn in the background until Close is called
+func (s *Scalar) startWorker(fn func(ctx context.Context)) {
+	if s.cancel == nil {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+	}
+
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		fn(s.ctx)
+	}()
+}
+
+// RenderDocs renders the API documentation to the provided writer as a self-contained
+// page with the Scalar bundle inlined, so it works without the handler serving the asset
 func (s *Scalar) RenderDocs(writer io.Writer) error {
 	if writer == nil {
 		return errors.New("writer cannot be nil")
 	}
 
-	tmpl, err := utils.ParseTemplateFromFS(embedTemplates, templatePattern)
+	page, err := s.renderPage()
 	if err != nil {
-		return fmt.Errorf("failed to parse template: %w", err)
+		return err
 	}
 
-	if err := tmpl.Execute(writer, s.config); err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
+	if _, err := writer.Write(page); err != nil {
+		return fmt.Errorf("failed to write page: %w", err)
 	}
 	return nil
 }
 
-// loadSpecFromFile loads specification content from a file
-func loadSpecFromFile(filePath string) (string, error) {
+// renderPage returns the standalone page of the configuration being served. It is rendered
+// once per snapshot, every reload swaps in a new snapshot and so invalidates it.
+// The returned bytes are shared and must not be modified.
+func (s *Scalar) renderPage() ([]byte, error) {
+	page, err := s.responses().page(standalonePage, serverTarget{})
+	if err != nil {
+		return nil, err
+	}
+	return page.body, nil
+}
+
+// render executes the page template. A non-empty base is the absolute path of the
+// mount point the bundle is then linked from.
+func (c *Config) render(base string) ([]byte, error) {
+	tmpl, err := pageTemplate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	reference, err := c.Reference.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	data := pageData{
+		Title:     c.Title,
+		Language:  c.Language,
+		Script:    c.script(base),
+		Reference: reference,
+	}
+	if len(c.Documents) > 0 {
+		if data.Sources, err = c.sourcesJSON(); err != nil {
+			return nil, err
+		}
+	} else {
+		data.Content = escapeScriptJSON([]byte(c.Content))
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(c.Script) + len(c.Content) + pageOverhead)
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// loadSpecFromFile loads specification content from a file. It also returns the files
+// read, the spec file first, followed by those its $refs bundle, even when loading fails.
+func loadSpecFromFile(ctx context.Context, filePath string) (string, []string, error) {
 	fileURL, err := normalizeFileURL(filePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to normalize file URL: %w", err)
+		return "", nil, fmt.Errorf("failed to normalize file URL: %w", err)
+	}
+	path := filepath.Clean(strings.TrimPrefix(fileURL, filePrefix))
+
+	if err := ctx.Err(); err != nil {
+		return "", nil, err
 	}
 
 	content, err := readFileFromURL(fileURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
+		return "", []string{path}, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	return normalizeSpecContent(string(content)), nil
+	files := &trackedFiles{specFiles: osFiles{}}
+	spec, err := bundleSpec(ctx, files, path, content)
+	return spec, append([]string{path}, files.names...), err
 }
 
-// loadSpecFromURL loads specification content from a URL
-func loadSpecFromURL(specURL string, client *http.Client) (string, error) {
-	if err := validateURL(specURL); err != nil {
-		return "", err
+// readBody reads a response body, decoding gzip the transport left encoded.
+// The size limit applies to the decoded content.
+func readBody(resp *http.Response, maxBodySize int64) ([]byte, error) {
+	tooLarge := &PolicyError{Host: resp.Request.URL.Hostname(), Err: ErrResponseTooLarge}
+	if maxBodySize > 0 && resp.ContentLength > maxBodySize {
+		return nil, tooLarge
 	}
 
-	if client == nil {
-		client = &http.Client{Timeout: defaultTimeout}
+	var body io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") && !resp.Uncompressed {
+		reader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip response body: %w", err)
+		}
+		defer reader.Close()
+		body = reader
+	}
+	if maxBodySize > 0 {
+		body = io.LimitReader(body, maxBodySize+1)
 	}
 
-	content, err := fetchFromURL(specURL, client)
+	content, err := io.ReadAll(body)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch from URL: %w", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
-
-	return normalizeSpecContent(string(content)), nil
+	if maxBodySize > 0 && int64(len(content)) > maxBodySize {
+		return nil, tooLarge
+	}
+	return content, nil
 }
 
 // validateURL validates if the URL is properly formatted and uses supported scheme
@@ -237,6 +535,11 @@ func validateURL(rawURL string) error {
 
 	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
+		// The parse error quotes the URL, which may hold credentials
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
 		return fmt.Errorf("%w: %s", ErrInvalidURL, err.Error())
 	}
 
@@ -248,38 +551,83 @@ func validateURL(rawURL string) error {
 	}
 }
 
-// fetchFromURL fetches content from HTTP/HTTPS URL
-func fetchFromURL(specURL string, client *http.Client) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), client.Timeout)
-	defer cancel()
+// urlValidators are the cache validators a source returned with its last response
+type urlValidators struct {
+	etag         string
+	lastModified string
+}
+
+// fetchOptions tune a URL fetch
+type fetchOptions struct {
+	setHeaders  func(*http.Request) error // Adds the caller's headers, if any
+	validators  urlValidators             // Validators of a previous response
+	maxBodySize int64                     // Zero leaves the body size unlimited
+}
+
+// urlResponse is the outcome of a conditional URL fetch
+type urlResponse struct {
+	content     []byte
+	statusCode  int
+	validators  urlValidators
+	notModified bool // The source confirmed the previous copy is still current
+}
 
+// fetchURL fetches content from HTTP/HTTPS URL, sending conditional headers
+// when validators from a previous response are known
+func fetchURL(ctx context.Context, specURL string, client *http.Client, options fetchOptions) (*urlResponse, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, specURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Accept", "application/json, application/yaml, text/yaml, */*")
-	req.Header.Set("User-Agent", "go-scalar/1.0")
+	req.Header.Set("User-Agent", defaultUserAgent)
+	if options.setHeaders != nil {
+		defaults := req.Header.Clone()
+		if err := options.setHeaders(req); err != nil {
+			return nil, err
+		}
+		client = stripOnRedirect(client, defaults, callerHeaders(defaults, req.Header))
+	}
+	validators := options.validators
+	if validators.etag != "" {
+		req.Header.Set("If-None-Match", validators.etag)
+	}
+	if validators.lastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.lastModified)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %s", ErrHTTPRequest, err.Error())
+		return nil, fmt.Errorf("%w: %w", ErrHTTPRequest, err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return &urlResponse{validators: validators, notModified: true}, nil
+	}
+
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("%w: HTTP %d %s", ErrHTTPRequest, resp.StatusCode, resp.Status)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, snippetSize))
+		return nil, &statusError{code: resp.StatusCode, status: resp.Status, snippet: snippet(string(body), 0)}
 	}
 
-	content, err := io.ReadAll(resp.Body)
+	content, err := readBody(resp, options.maxBodySize)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
 	if len(content) == 0 {
 		return nil, ErrEmptyResponse
 	}
-	return content, nil
+	return &urlResponse{
+		content:    content,
+		statusCode: resp.StatusCode,
+		validators: urlValidators{
+			etag:         resp.Header.Get("ETag"),
+			lastModified: resp.Header.Get("Last-Modified"),
+		},
+	}, nil
 }
 
 // normalizeFileURL ensures the file path is a proper file:// URL
@@ -329,30 +677,6 @@ func readFileFromURL(fileURL string) ([]byte, error) {
 	return content, nil
 }
 
-// normalizeSpecContent normalizes specification content to JSON string
-func normalizeSpecContent(specContent any) string {
-	switch spec := specContent.(type) {
-	case func() map[string]any:
-		// Function that returns map
-		result := spec()
-		if jsonData, err := json.Marshal(result); err == nil {
-			return string(jsonData)
-		}
-	case map[string]any:
-		// Direct map
-		if jsonData, err := json.Marshal(spec); err == nil {
-			return string(jsonData)
-		}
-	case string:
-		// String content - validate if it's JSON
-		spec = strings.TrimSpace(spec)
-		if isValidJSON(spec) {
-			return spec
-		}
-	}
-	return ""
-}
-
 // isValidJSON checks if a string is valid JSON
 func isValidJSON(s string) bool {
 	var js json.RawMessage
@@ -416,6 +740,11 @@ func (b *Builder) Build() (*Scalar, error) {
 	return NewScalar(b.options...)
 }
 
+// BuildContext creates the Scalar instance, loading specs under ctx
+func (b *Builder) BuildContext(ctx context.Context) (*Scalar, error) {
+	return NewScalarContext(ctx, b.options...)
+}
+
 // Miscellaneous
 
 // FromFile creates a Scalar instance from a file path
@@ -442,49 +771,11 @@ func FromContent(content string, options ...Option) (*Scalar, error) {
 	return NewScalar(opts...)
 }
 
-func escapeJSString(raw string) string {
-	if raw == "" {
-		return raw
-	}
-
-	var builder strings.Builder
-	builder.Grow(len(raw) + len(raw)/10)
-
-	for _, r := range raw {
-		switch r {
-		case '`':
-			builder.WriteString("\\`")
-		case '"':
-			builder.WriteString(`\"`)
-		case '\\':
-			builder.WriteString(`\\`)
-		case '\n':
-			builder.WriteString(`\n`)
-		case '\r':
-			builder.WriteString(`\r`)
-		case '\t':
-			builder.WriteString(`\t`)
-		case '\f':
-			builder.WriteString(`\f`)
-		case '\b':
-			builder.WriteString(`\b`)
-		case '\v':
-			builder.WriteString(`\v`)
-		case '\u0000':
-			builder.WriteString(`\u0000`)
-		default:
-			if r < 32 || r == 127 {
-				builder.WriteString(`\u`)
-				hex := "0123456789abcdef"
-				builder.WriteByte(hex[(r>>12)&0xf])
-				builder.WriteByte(hex[(r>>8)&0xf])
-				builder.WriteByte(hex[(r>>4)&0xf])
-				builder.WriteByte(hex[r&0xf])
-			} else {
-				builder.WriteRune(r)
-			}
-		}
-	}
-
-	return builder.String()
+// escapeScriptJSON escapes a JSON document so it can be embedded in a script element.
+// <, >, & and the JS line terminators U+2028/U+2029 become \u escapes, so no value can
+// close the script tag or open an HTML comment, and the JSON stays equivalent.
+func escapeScriptJSON(data []byte) template.JS {
+	var buf bytes.Buffer
+	json.HTMLEscape(&buf, data)
+	return template.JS(buf.String())
 }
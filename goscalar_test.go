@@ -2,6 +2,7 @@ package goscalar
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -14,6 +15,8 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"github.com/swaggo/swag"
+
+	"github.com/JhonatanRSantos/goscalar/utils"
 )
 
 func Test_WithTitle(t *testing.T) {
@@ -373,7 +376,11 @@ func Test_WithURL(t *testing.T) {
 				},
 			}
 
+			// The spec is fetched once every option is applied
 			err := WithURL(tt.url)(scalar)
+			if err == nil {
+				err = scalar.loadURL()
+			}
 
 			if tt.expectError {
 				require.Error(t, err)
@@ -488,6 +495,80 @@ func Test_RenderDocs(t *testing.T) {
 	}
 }
 
+func Test_RenderPageCache(t *testing.T) {
+	scalar, err := NewScalar(WithTitle("Cached"), WithSpecContent(`{"openapi": "3.0.0", "info": {"title": "Version 1"}}`))
+	require.NoError(t, err)
+	defer scalar.Close()
+
+	first, err := scalar.renderPage()
+	require.NoError(t, err)
+	second, err := scalar.renderPage()
+	require.NoError(t, err)
+	require.Same(t, &first[0], &second[0], "the page is rendered once per snapshot")
+
+	var buf bytes.Buffer
+	require.NoError(t, scalar.RenderDocs(&buf))
+	require.Equal(t, first, buf.Bytes())
+
+	// An unchanged spec keeps the snapshot and its page
+	require.False(t, scalar.swapContent(scalar.snapshot().Content))
+	unchanged, err := scalar.renderPage()
+	require.NoError(t, err)
+	require.Same(t, &first[0], &unchanged[0])
+
+	// Background reloads invalidate the page
+	require.True(t, scalar.swapContent(`{"openapi": "3.0.0", "info": {"title": "Version 2"}}`))
+	reloaded, err := scalar.renderPage()
+	require.NoError(t, err)
+	require.Contains(t, string(reloaded), "Version 2")
+	require.Contains(t, string(reloaded), "<title>Cached</title>")
+
+	// So do updates
+	require.NoError(t, scalar.Update(WithTitle("Updated"), WithSpecContent(`{"openapi": "3.0.0", "info": {"title": "Version 3"}}`)))
+	rec := httptest.NewRecorder()
+	scalar.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "Version 3")
+	require.Contains(t, rec.Body.String(), "<title>Updated</title>")
+}
+
+func Benchmark_RenderDocs(b *testing.B) {
+	scalar, err := NewScalar(WithInlineScript(), WithSpecContent(`{"openapi": "3.0.0", "info": {"title": "Bench API", "version": "1.0.0"}}`))
+	require.NoError(b, err)
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if err := scalar.RenderDocs(io.Discard); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	// Every render executes the template, as a reload before each request would
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := scalar.snapshot().render(""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	// Parsing the template for every render, as RenderDocs used to
+	b.Run("parsed per render", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := utils.ParseTemplateFromFS(embedTemplates, templatePattern); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := scalar.snapshot().render(""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
 func Test_ValidateURL(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -545,7 +626,7 @@ func Test_ValidateURL(t *testing.T) {
 	}
 }
 
-func Test_FetchFromURL(t *testing.T) {
+func Test_FetchURL(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case "/valid":
@@ -570,6 +651,7 @@ func Test_FetchFromURL(t *testing.T) {
 		name        string
 		url         string
 		client      *http.Client
+		canceled    bool
 		expectError bool
 		expectedErr error
 	}{
@@ -599,11 +681,25 @@ func Test_FetchFromURL(t *testing.T) {
 			client:      &http.Client{Timeout: 10 * time.Millisecond},
 			expectError: true,
 		},
+		{
+			name:        "canceled context",
+			url:         server.URL + "/valid",
+			client:      &http.Client{Timeout: 5 * time.Second},
+			canceled:    true,
+			expectError: true,
+			expectedErr: context.Canceled,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			content, err := fetchFromURL(tt.url, tt.client)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.canceled {
+				cancel()
+			}
+
+			response, err := fetchURL(ctx, tt.url, tt.client, fetchOptions{})
 
 			if tt.expectError {
 				require.Error(t, err)
@@ -612,7 +708,7 @@ func Test_FetchFromURL(t *testing.T) {
 				}
 			} else {
 				require.NoError(t, err)
-				require.NotEmpty(t, content)
+				require.NotEmpty(t, response.content)
 			}
 		})
 	}
@@ -665,80 +761,6 @@ func Test_NormalizeFileURL(t *testing.T) {
 	}
 }
 
-func Test_NormalizeSpecContent(t *testing.T) {
-	validJSON := `{"openapi": "3.0.0", "info": {"title": "Test API", "version": "1.0.0"}}`
-	validMap := map[string]any{
-		"openapi": "3.0.0",
-		"info": map[string]any{
-			"title":   "Test API",
-			"version": "1.0.0",
-		},
-	}
-
-	tests := []struct {
-		name     string
-		content  any
-		expected string
-	}{
-		{
-			name:     "valid JSON string",
-			content:  validJSON,
-			expected: validJSON,
-		},
-		{
-			name:     "valid map",
-			content:  validMap,
-			expected: `{"info":{"title":"Test API","version":"1.0.0"},"openapi":"3.0.0"}`,
-		},
-		{
-			name: "function returning map",
-			content: func() map[string]any {
-				return validMap
-			},
-			expected: `{"info":{"title":"Test API","version":"1.0.0"},"openapi":"3.0.0"}`,
-		},
-		{
-			name:     "invalid JSON string",
-			content:  "not json",
-			expected: "",
-		},
-		{
-			name:     "empty string",
-			content:  "",
-			expected: "",
-		},
-		{
-			name:     "whitespace string",
-			content:  "   ",
-			expected: "",
-		},
-		{
-			name:     "nil content",
-			content:  nil,
-			expected: "",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := normalizeSpecContent(tt.content)
-
-			if tt.expected == "" {
-				require.Empty(t, result)
-			} else {
-				require.NotEmpty(t, result)
-				// For JSON comparison, we need to normalize both strings
-				if tt.expected != "" {
-					var expectedJSON, resultJSON any
-					require.NoError(t, json.Unmarshal([]byte(tt.expected), &expectedJSON))
-					require.NoError(t, json.Unmarshal([]byte(result), &resultJSON))
-					require.Equal(t, expectedJSON, resultJSON)
-				}
-			}
-		})
-	}
-}
-
 func Test_IsValidJSON(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -800,97 +822,161 @@ func Test_IsValidJSON(t *testing.T) {
 	}
 }
 
-func Test_EscapeJSString(t *testing.T) {
+func Test_EscapeScriptJSON(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    string
 		expected string
 	}{
 		{
-			name:     "empty string",
-			input:    "",
-			expected: "",
+			name:     "empty object",
+			input:    `{}`,
+			expected: `{}`,
 		},
 		{
 			name:     "no special characters",
-			input:    "hello world",
-			expected: "hello world",
+			input:    `{"title":"hello world"}`,
+			expected: `{"title":"hello world"}`,
 		},
 		{
-			name:     "backtick",
-			input:    "hello `world`",
-			expected: "hello \\`world\\`",
+			name:     "closing script tag",
+			input:    `{"example":"</script><script>alert(1)</script>"}`,
+			expected: `{"example":"\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e"}`,
 		},
 		{
-			name:     "double quote",
-			input:    `hello "world"`,
-			expected: `hello \"world\"`,
+			name:     "HTML comment",
+			input:    `{"description":"<!-- hidden -->"}`,
+			expected: `{"description":"\u003c!-- hidden --\u003e"}`,
 		},
 		{
-			name:     "backslash",
-			input:    `hello \world`,
-			expected: `hello \\world`,
+			name:     "ampersand",
+			input:    `{"description":"a && b"}`,
+			expected: `{"description":"a \u0026\u0026 b"}`,
 		},
 		{
-			name:     "newline",
-			input:    "hello\nworld",
-			expected: "hello\\nworld",
+			name:     "line separators",
+			input:    "{\"description\":\"a\u2028b\u2029c\"}",
+			expected: `{"description":"a\u2028b\u2029c"}`,
 		},
 		{
-			name:     "carriage return",
-			input:    "hello\rworld",
-			expected: "hello\\rworld",
+			name:     "template literal syntax is kept",
+			input:    "{\"description\":\"${alert(1)} `tick`\"}",
+			expected: "{\"description\":\"${alert(1)} `tick`\"}",
 		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := escapeScriptJSON([]byte(tt.input))
+			require.Equal(t, tt.expected, string(result))
+
+			// The escaped document decodes to the original one
+			var expected, decoded any
+			require.NoError(t, json.Unmarshal([]byte(tt.input), &expected))
+			require.NoError(t, json.Unmarshal([]byte(result), &decoded))
+			require.Equal(t, expected, decoded)
+		})
+	}
+}
+
+func Test_RenderDocsHostileInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    string
+		language string
+		spec     string
+	}{
 		{
-			name:     "tab",
-			input:    "hello\tworld",
-			expected: "hello\\tworld",
+			name:  "script tag in description",
+			title: "API",
+			spec:  `{"openapi":"3.0.0","info":{"title":"API","version":"1","description":"</script><script>alert(1)</script>"}}`,
 		},
 		{
-			name:     "form feed",
-			input:    "hello\fworld",
-			expected: "hello\\fworld",
+			name:  "upper case script tag in example",
+			title: "API",
+			spec:  `{"openapi":"3.0.0","info":{"title":"API","version":"1"},"x-example":"</SCRIPT ><img src=x onerror=alert(1)>"}`,
 		},
 		{
-			name:     "backspace",
-			input:    "hello\bworld",
-			expected: "hello\\bworld",
+			name:  "template literal interpolation",
+			title: "API",
+			spec:  "{\"openapi\":\"3.0.0\",\"info\":{\"title\":\"${alert(document.cookie)}\",\"version\":\"1\",\"description\":\"`+alert(1)+`\"}}",
 		},
 		{
-			name:     "vertical tab",
-			input:    "hello\vworld",
-			expected: "hello\\vworld",
+			name:  "HTML comment and CDATA",
+			title: "API",
+			spec:  `{"openapi":"3.0.0","info":{"title":"<!--<script>","version":"1","description":"]]><![CDATA["}}`,
 		},
 		{
-			name:     "null character",
-			input:    "hello\u0000world",
-			expected: "hello\\u0000world",
+			name:  "line separators",
+			title: "API",
+			spec:  "{\"openapi\":\"3.0.0\",\"info\":{\"title\":\"a\u2028b\u2029c\",\"version\":\"1\"}}",
 		},
 		{
-			name:     "control character",
-			input:    "hello\u0001world",
-			expected: "hello\\u0001world",
+			name:  "hostile title",
+			title: `</title><script>alert(1)</script>`,
+			spec:  `{"openapi":"3.0.0","info":{"title":"API","version":"1"}}`,
 		},
 		{
-			name:     "delete character",
-			input:    "hello\u007fworld",
-			expected: "hello\\u007fworld",
+			name:     "hostile language",
+			title:    "API",
+			language: `en" onload="alert(1)`,
+			spec:     `{"openapi":"3.0.0","info":{"title":"API","version":"1"}}`,
 		},
 		{
-			name:     "mixed special characters",
-			input:    "hello\n\t\"world`",
-			expected: "hello\\n\\t\\\"world\\`",
+			name:  "hostile YAML spec",
+			title: "API",
+			spec:  "openapi: 3.0.0\ninfo:\n  title: API\n  version: '1'\n  description: \"</script><script>alert(1)</script>\"\n",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := escapeJSString(tt.input)
-			require.Equal(t, tt.expected, result)
+			scalar, err := NewScalar(
+				WithTitle(tt.title),
+				WithLanguage(tt.language),
+				WithSpecContent(tt.spec),
+				WithReferenceConfig(ReferenceConfig{CustomCSS: "</style></script><script>alert(1)</script>"}),
+			)
+			require.NoError(t, err)
+
+			// The served page links the bundle, RenderDocs would inline its script strings
+			rendered := servePage(t, scalar)
+
+			// Only the four script elements of the template are present
+			require.Equal(t, 4, strings.Count(strings.ToLower(rendered), "<script"))
+			require.Equal(t, 4, strings.Count(strings.ToLower(rendered), "</script"))
+			require.NotContains(t, rendered, "<img")
+			require.NotContains(t, rendered, "<!--<script>")
+			require.NotContains(t, rendered, "\u2028")
+			require.NotContains(t, rendered, `onload="`)
+
+			// The data block decodes to the normalized spec
+			content := extractScriptBlock(t, rendered, "api-reference-content")
+			var expected, decoded any
+			require.NoError(t, json.Unmarshal([]byte(scalar.config.Content), &expected))
+			require.NoError(t, json.Unmarshal([]byte(content), &decoded))
+			require.Equal(t, expected, decoded)
+
+			config := extractScriptBlock(t, rendered, "api-reference-config")
+			var reference ReferenceConfig
+			require.NoError(t, json.Unmarshal([]byte(config), &reference))
+			require.Equal(t, "</style></script><script>alert(1)</script>", reference.CustomCSS)
 		})
 	}
 }
 
+// extractScriptBlock returns the text of the script element with the given id
+func extractScriptBlock(t *testing.T, page, id string) string {
+	t.Helper()
+
+	start := strings.Index(page, `<script id="`+id+`" type="application/json">`)
+	require.NotEqual(t, -1, start)
+	start = strings.Index(page[start:], ">") + start + 1
+	end := strings.Index(page[start:], "</script>")
+	require.NotEqual(t, -1, end)
+	return page[start : start+end]
+}
+
 func Test_Builder(t *testing.T) {
 	validContent := `{"openapi": "3.0.0", "info": {"title": "Test API", "version": "1.0.0"}}`
 
@@ -1104,7 +1190,7 @@ func Test_LoadSpecFromFile(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			content, err := loadSpecFromFile(tt.filePath)
+			content, _, err := loadSpecFromFile(context.Background(), tt.filePath)
 
 			if tt.expectError {
 				require.Error(t, err)
@@ -1116,7 +1202,7 @@ func Test_LoadSpecFromFile(t *testing.T) {
 	}
 }
 
-func Test_LoadSpecFromURL(t *testing.T) {
+func Test_URLSource(t *testing.T) {
 	// Create test server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -1166,7 +1252,7 @@ func Test_LoadSpecFromURL(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			content, err := loadSpecFromURL(tt.specURL, tt.client)
+			content, _, err := loadSpecSource(context.Background(), URLSource{URL: tt.specURL, Client: tt.client})
 
 			if tt.expectError {
 				require.Error(t, err)
@@ -0,0 +1,193 @@
+package goscalar
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Spec endpoints, relative to the mount prefix
+	specJSONPath = "openapi.json"
+	specYAMLPath = "openapi.yaml"
+
+	// Content types
+	contentTypeHTML = "text/html; charset=utf-8"
+	contentTypeJSON = "application/json; charset=utf-8"
+	contentTypeYAML = "application/yaml; charset=utf-8"
+)
+
+// docsHandler serves the documentation page and the raw spec under a mount prefix
+type docsHandler struct {
+	scalar *Scalar
+	prefix string
+}
+
+// ServeHTTP serves the documentation page at "/", the normalized spec at
+// "/openapi.json", "/openapi.yaml" and "/openapi", which negotiates its format, the
+// spec of each WithSource document at "/<slug>/openapi.json", "/<slug>/openapi.yaml"
+// and "/<slug>/openapi" and the Scalar bundle under "/assets/".
+// Paths are expected relative to the mount point, so mount it with
+// http.StripPrefix or use Handler(prefix) instead.
+func (s *Scalar) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Handler("").ServeHTTP(w, r)
+}
+
+// Handler returns an http.Handler serving the documentation under the given prefix,
+// e.g. Handler("/docs") serves "/docs/", "/docs/openapi.json" and "/docs/openapi.yaml"
+func (s *Scalar) Handler(prefix string) http.Handler {
+	return &docsHandler{
+		scalar: s,
+		prefix: normalizePrefix(prefix),
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *docsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	relPath, ok := strings.CutPrefix(r.URL.Path, h.prefix)
+	if !ok || (relPath != "" && !strings.HasPrefix(relPath, "/")) {
+		http.NotFound(w, r)
+		return
+	}
+
+	cache := h.scalar.responses()
+	base, external := cache.config.externalBase(r, h.mountPath(r, relPath))
+	target := cache.config.serverTarget(r, base)
+
+	switch name := strings.TrimPrefix(relPath, "/"); {
+	case name == "":
+		// The page links to its sibling endpoints relatively, so it must end with a slash
+		if relPath == "" {
+			redirect := slashRedirectTarget(r)
+			if external {
+				redirect = externalRedirectTarget(r, base)
+			}
+			http.Redirect(w, r, redirect, http.StatusMovedPermanently)
+			return
+		}
+
+		var pageBase string
+		if external {
+			pageBase = (&url.URL{Path: base.Path + "/"}).EscapedPath()
+		}
+		h.servePage(w, r, cache, pageBase, target)
+	case name == assetsPath+scriptAsset().name:
+		scriptAsset().serve(w, r)
+	default:
+		// Documents added with WithSource serve their spec under their slug
+		slug, file, ok := strings.Cut(name, "/")
+		if !ok {
+			slug, file = "", name
+		}
+		h.serveSpec(w, r, cache, slug, file, target)
+	}
+}
+
+// serveSpec writes the spec of the document with the given slug as JSON or YAML.
+// The "openapi" file negotiates the format with the Accept header or ?format=.
+func (h *docsHandler) serveSpec(w http.ResponseWriter, r *http.Request, cache *responseCache, slug, file string, target serverTarget) {
+	query, err := parseSpecQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if file == specPath {
+		w.Header().Add("Vary", "Accept")
+		if file = query.format; file == "" {
+			var ok bool
+			if file, ok = negotiateSpecFormat(r.Header.Get("Accept")); !ok {
+				http.Error(w, "the spec is available as application/json or application/yaml", http.StatusNotAcceptable)
+				return
+			}
+		}
+	}
+
+	spec, ok, err := cache.spec(slug, file, query.layout, target)
+	switch {
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	case !ok:
+		http.NotFound(w, r)
+	default:
+		if query.download {
+			setAttachment(w, slug, file)
+		}
+		spec.serve(w, r)
+	}
+}
+
+// servePage writes the documentation page linking the bundle from base
+func (h *docsHandler) servePage(w http.ResponseWriter, r *http.Request, cache *responseCache, base string, target serverTarget) {
+	page, err := cache.page(base, target)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	page.serve(w, r)
+}
+
+// writeBody writes a complete response, omitting the body for HEAD requests
+func writeBody(w http.ResponseWriter, r *http.Request, contentType string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(body)
+}
+
+// normalizePrefix returns the prefix with a leading slash and without a trailing one
+func normalizePrefix(prefix string) string {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
+
+// slashRedirectTarget returns the original request path with a trailing slash.
+// The request URI is preferred because http.StripPrefix rewrites URL.Path.
+func slashRedirectTarget(r *http.Request) string {
+	target := r.URL
+	if parsed, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		target = parsed
+	}
+
+	// Collapse leading slashes so the target can never become a protocol-relative URL
+	path := "/" + strings.TrimLeft(target.Path, "/")
+	if path != "/" {
+		path += "/"
+	}
+
+	redirect := url.URL{Path: path, RawQuery: target.RawQuery}
+	return redirect.String()
+}
+
+// mountPath returns the path of the mount point on this server. The request URI is
+// preferred because http.StripPrefix removes the prefix it serves under.
+func (h *docsHandler) mountPath(r *http.Request, relPath string) string {
+	requestPath := r.URL.Path
+	if parsed, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		requestPath = parsed.Path
+	}
+	if mount, ok := strings.CutSuffix(requestPath, relPath); ok {
+		return normalizePrefix(mount)
+	}
+	return h.prefix
+}
+
+// externalRedirectTarget returns the slash form of the external mount point. Only
+// its path is used, clients already reach the server through the external host.
+func externalRedirectTarget(r *http.Request, base *url.URL) string {
+	redirect := url.URL{Path: base.Path + "/", RawQuery: r.URL.RawQuery}
+	return redirect.String()
+}
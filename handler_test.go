@@ -0,0 +1,386 @@
+package goscalar
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_Handler(t *testing.T) {
+	validContent := `{"openapi": "3.0.0", "info": {"title": "Handler Test API", "version": "1.0.0"}}`
+
+	scalar, err := NewScalar(WithTitle("Handler Docs"), WithSpecContent(validContent))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name             string
+		handler          http.Handler
+		method           string
+		target           string
+		expectedStatus   int
+		expectedType     string
+		expectedLocation string
+		expectedBody     string
+		expectEmptyBody  bool
+	}{
+		{
+			name:           "page at root",
+			handler:        scalar,
+			method:         http.MethodGet,
+			target:         "/",
+			expectedStatus: http.StatusOK,
+			expectedType:   contentTypeHTML,
+			expectedBody:   "Handler Docs",
+		},
+		{
+			name:           "spec as JSON",
+			handler:        scalar,
+			method:         http.MethodGet,
+			target:         "/openapi.json",
+			expectedStatus: http.StatusOK,
+			expectedType:   contentTypeJSON,
+			expectedBody:   validContent,
+		},
+		{
+			name:           "spec as YAML",
+			handler:        scalar,
+			method:         http.MethodGet,
+			target:         "/openapi.yaml",
+			expectedStatus: http.StatusOK,
+			expectedType:   contentTypeYAML,
+			expectedBody:   "title: Handler Test API",
+		},
+		{
+			name:            "HEAD has no body",
+			handler:         scalar,
+			method:          http.MethodHead,
+			target:          "/openapi.json",
+			expectedStatus:  http.StatusOK,
+			expectedType:    contentTypeJSON,
+			expectEmptyBody: true,
+		},
+		{
+			name:           "POST is not allowed",
+			handler:        scalar,
+			method:         http.MethodPost,
+			target:         "/",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "unknown path",
+			handler:        scalar,
+			method:         http.MethodGet,
+			target:         "/unknown",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "prefixed page",
+			handler:        scalar.Handler("/docs"),
+			method:         http.MethodGet,
+			target:         "/docs/",
+			expectedStatus: http.StatusOK,
+			expectedType:   contentTypeHTML,
+			expectedBody:   "Handler Docs",
+		},
+		{
+			name:           "prefixed spec with trailing slash prefix",
+			handler:        scalar.Handler("/docs/"),
+			method:         http.MethodGet,
+			target:         "/docs/openapi.json",
+			expectedStatus: http.StatusOK,
+			expectedType:   contentTypeJSON,
+			expectedBody:   validContent,
+		},
+		{
+			name:             "bare prefix redirects",
+			handler:          scalar.Handler("/docs"),
+			method:           http.MethodGet,
+			target:           "/docs?tab=models",
+			expectedStatus:   http.StatusMovedPermanently,
+			expectedLocation: "/docs/?tab=models",
+		},
+		{
+			name:             "stripped prefix redirects to original path",
+			handler:          http.StripPrefix("/docs", scalar),
+			method:           http.MethodGet,
+			target:           "/docs",
+			expectedStatus:   http.StatusMovedPermanently,
+			expectedLocation: "/docs/",
+		},
+		{
+			name:           "stripped prefix serves spec",
+			handler:        http.StripPrefix("/docs", scalar),
+			method:         http.MethodGet,
+			target:         "/docs/openapi.json",
+			expectedStatus: http.StatusOK,
+			expectedBody:   validContent,
+		},
+		{
+			name:           "path outside prefix",
+			handler:        scalar.Handler("/docs"),
+			method:         http.MethodGet,
+			target:         "/docsx/openapi.json",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			rec := httptest.NewRecorder()
+
+			tt.handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedType != "" {
+				require.Equal(t, tt.expectedType, rec.Header().Get("Content-Type"))
+			}
+			if tt.expectedLocation != "" {
+				require.Equal(t, tt.expectedLocation, rec.Header().Get("Location"))
+			}
+			if tt.expectedBody != "" {
+				require.Contains(t, rec.Body.String(), tt.expectedBody)
+			}
+			if tt.expectEmptyBody {
+				require.Empty(t, rec.Body.String())
+				require.NotEmpty(t, rec.Header().Get("Content-Length"))
+			}
+			if tt.expectedStatus == http.StatusMethodNotAllowed {
+				require.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+			}
+		})
+	}
+}
+
+func Test_NormalizePrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		expected string
+	}{
+		{name: "empty", prefix: "", expected: ""},
+		{name: "root", prefix: "/", expected: ""},
+		{name: "no slashes", prefix: "docs", expected: "/docs"},
+		{name: "trailing slash", prefix: "/docs/", expected: "/docs"},
+		{name: "nested", prefix: " /api/docs ", expected: "/api/docs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, normalizePrefix(tt.prefix))
+		})
+	}
+}
+
+func Test_SlashRedirectTarget(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		expected string
+	}{
+		{name: "plain path", target: "/docs", expected: "/docs/"},
+		{name: "keeps query", target: "/docs?a=b", expected: "/docs/?a=b"},
+		{name: "collapses leading slashes", target: "//evil.example/docs", expected: "/evil.example/docs/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RequestURI = tt.target
+			require.Equal(t, tt.expected, slashRedirectTarget(req))
+		})
+	}
+}
+
+func Test_JSONToYAML(t *testing.T) {
+	content := `{"openapi":"3.0.0","info":{"version":"1.0.0","title":"Test"},"x-int":1,"x-float":1.5,"x-bool":false,"x-null":null,"x-list":["a","true"]}`
+
+	result, err := jsonToYAML(content)
+	require.NoError(t, err)
+
+	// Key order is preserved
+	require.Less(t, strings.Index(result, "version"), strings.Index(result, "title"))
+	require.Contains(t, result, `openapi: 3.0.0`)
+
+	// Converting back yields the same document
+	roundTrip, err := parseSpecContent(result)
+	require.NoError(t, err)
+	require.Equal(t, content, roundTrip)
+
+	var decoded map[string]any
+	require.NoError(t, yaml.Unmarshal([]byte(result), &decoded))
+	require.Equal(t, "true", decoded["x-list"].([]any)[1])
+
+	_, err = jsonToYAML(`{"broken": `)
+	require.Error(t, err)
+}
+
+func Test_HandlerCaching(t *testing.T) {
+	validContent := `{"openapi": "3.0.0", "info": {"title": "Cached API", "version": "1.0.0"}}`
+
+	scalar, err := NewScalar(WithSpecContent(validContent))
+	require.NoError(t, err)
+	defer scalar.Close()
+
+	serve := func(target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		scalar.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, target := range []string{"/", "/openapi.json", "/openapi.yaml"} {
+		first := serve(target, nil)
+		require.Equal(t, http.StatusOK, first.Code)
+		etag := first.Header().Get("ETag")
+		lastModified := first.Header().Get("Last-Modified")
+
+		tests := []struct {
+			name             string
+			headers          map[string]string
+			expectedStatus   int
+			expectedEncoding string
+		}{
+			{name: "matching ETag", headers: map[string]string{"If-None-Match": etag}, expectedStatus: http.StatusNotModified},
+			{name: "other ETag", headers: map[string]string{"If-None-Match": `"other"`}, expectedStatus: http.StatusOK},
+			{name: "not modified since", headers: map[string]string{"If-Modified-Since": lastModified}, expectedStatus: http.StatusNotModified},
+			{name: "modified since", headers: map[string]string{"If-Modified-Since": "Mon, 02 Jan 2006 15:04:05 GMT"}, expectedStatus: http.StatusOK},
+			{
+				name:           "ETag takes precedence",
+				headers:        map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": lastModified},
+				expectedStatus: http.StatusOK,
+			},
+			{name: "gzip", headers: map[string]string{"Accept-Encoding": "gzip"}, expectedStatus: http.StatusOK, expectedEncoding: encodingGzip},
+			{name: "brotli", headers: map[string]string{"Accept-Encoding": "gzip, br"}, expectedStatus: http.StatusOK, expectedEncoding: encodingBrotli},
+		}
+
+		for _, tt := range tests {
+			t.Run(target+" "+tt.name, func(t *testing.T) {
+				rec := serve(target, tt.headers)
+				require.Equal(t, tt.expectedStatus, rec.Code)
+				require.Equal(t, revalidateCacheControl, rec.Header().Get("Cache-Control"))
+				require.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+				require.Equal(t, lastModified, rec.Header().Get("Last-Modified"))
+
+				if tt.expectedStatus == http.StatusNotModified {
+					require.Empty(t, rec.Body.Bytes())
+					return
+				}
+				require.Equal(t, tt.expectedEncoding, rec.Header().Get("Content-Encoding"))
+				require.Equal(t, first.Body.Bytes(), decodeBody(t, tt.expectedEncoding, rec.Body.Bytes()))
+			})
+		}
+	}
+
+	t.Run("reloads change the ETag", func(t *testing.T) {
+		before := serve("/openapi.json", nil).Header().Get("ETag")
+		require.True(t, scalar.swapContent(`{"openapi": "3.0.0", "info": {"title": "Reloaded API", "version": "2.0.0"}}`))
+
+		rec := serve("/openapi.json", map[string]string{"If-None-Match": before})
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), "Reloaded API")
+		require.NotEqual(t, before, rec.Header().Get("ETag"))
+	})
+}
+
+func Test_WithCacheMaxAge(t *testing.T) {
+	validContent := `{"openapi": "3.0.0", "info": {"title": "Cached API", "version": "1.0.0"}}`
+
+	tests := []struct {
+		name                 string
+		maxAge               time.Duration
+		expectedCacheControl string
+		expectedErr          error
+	}{
+		{name: "zero revalidates", maxAge: 0, expectedCacheControl: revalidateCacheControl},
+		{name: "below a second revalidates", maxAge: time.Millisecond, expectedCacheControl: revalidateCacheControl},
+		{name: "max-age", maxAge: 5 * time.Minute, expectedCacheControl: "max-age=300"},
+		{name: "negative", maxAge: -time.Second, expectedErr: ErrInvalidCacheMaxAge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scalar, err := NewBuilder().Content(validContent).CacheMaxAge(tt.maxAge).Build()
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			for _, target := range []string{"/", "/openapi.json"} {
+				rec := httptest.NewRecorder()
+				scalar.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+				require.Equal(t, tt.expectedCacheControl, rec.Header().Get("Cache-Control"))
+			}
+
+			// The bundle is immutable whatever the setting
+			rec := httptest.NewRecorder()
+			scalar.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+assetsPath+scriptAsset().name, nil))
+			require.Equal(t, immutableCacheControl, rec.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func Test_LRUCache(t *testing.T) {
+	t.Run("evicts the least recently used", func(t *testing.T) {
+		cache := newLRUCache[string](2)
+		builds := 0
+		get := func(key string) {
+			value, err := cache.get(key, func() (string, error) {
+				builds++
+				return key, nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, key, value)
+		}
+
+		get("a")
+		get("b")
+		get("a")
+		get("c")
+		require.Equal(t, 3, builds)
+		require.Equal(t, 2, cache.len())
+
+		// b was evicted, a was not
+		get("a")
+		require.Equal(t, 3, builds)
+		get("b")
+		require.Equal(t, 4, builds)
+	})
+
+	t.Run("builds without holding the lock", func(t *testing.T) {
+		cache := newLRUCache[int](0)
+		building := make(chan struct{})
+		release := make(chan struct{})
+
+		done := make(chan int)
+		for i := 0; i < 2; i++ {
+			go func() {
+				value, _ := cache.get("slow", func() (int, error) {
+					close(building)
+					<-release
+					return 1, nil
+				})
+				done <- value
+			}()
+		}
+		<-building
+
+		// Other keys are served while the slow value builds, once for every caller
+		value, err := cache.get("fast", func() (int, error) { return 2, nil })
+		require.NoError(t, err)
+		require.Equal(t, 2, value)
+
+		close(release)
+		require.Equal(t, 1, <-done)
+		require.Equal(t, 1, <-done)
+	})
+}
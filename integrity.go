@@ -0,0 +1,158 @@
+package goscalar
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrVerificationFailed  = errors.New("spec verification failed")
+	ErrInvalidVerification = errors.New("invalid spec verification")
+	ErrVerifyRequiresURL   = errors.New("verifying requires a spec loaded with WithURL()")
+)
+
+// signatureSuffix is appended to the spec URL to get its signature URL
+const signatureSuffix = ".sig"
+
+// verification holds the checks the fetched spec must pass
+type verification struct {
+	digest       []byte            // Pinned SHA-256 digest
+	publicKey    ed25519.PublicKey // Key of the ed25519 signature
+	signature    []byte            // Detached signature, nil when fetched from the signature URL
+	signatureURL bool              // Fetch the signature from the spec URL with a .sig suffix
+}
+
+// enabled reports whether any check is configured
+func (v verification) enabled() bool {
+	return v.digest != nil || v.publicKey != nil
+}
+
+// WithSHA256 pins the hex-encoded SHA-256 digest of the WithURL spec. The digest is
+// computed over the response body, so it matches `sha256sum` of the published file.
+func WithSHA256(digest string) Option {
+	return func(s *Scalar) error {
+		decoded, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(digest), "sha256:"))
+		if err != nil || len(decoded) != sha256.Size {
+			return fmt.Errorf("%w: SHA-256 digest must be %d hex characters", ErrInvalidVerification, sha256.Size*2)
+		}
+		s.verification.digest = decoded
+		return nil
+	}
+}
+
+// WithSignature verifies the WithURL spec against a detached ed25519 signature
+func WithSignature(publicKey ed25519.PublicKey, signature []byte) Option {
+	return func(s *Scalar) error {
+		if len(publicKey) != ed25519.PublicKeySize {
+			return fmt.Errorf("%w: ed25519 public key must be %d bytes", ErrInvalidVerification, ed25519.PublicKeySize)
+		}
+		if len(signature) != ed25519.SignatureSize {
+			return fmt.Errorf("%w: ed25519 signature must be %d bytes", ErrInvalidVerification, ed25519.SignatureSize)
+		}
+		s.verification.publicKey = publicKey
+		s.verification.signature = signature
+		s.verification.signatureURL = false
+		return nil
+	}
+}
+
+// WithSignatureURL verifies the WithURL spec against the ed25519 signature published
+// next to it, at the spec URL with a .sig suffix. The signature is raw or base64 encoded,
+// and fetched again with every refresh.
+func WithSignatureURL(publicKey ed25519.PublicKey) Option {
+	return func(s *Scalar) error {
+		if len(publicKey) != ed25519.PublicKeySize {
+			return fmt.Errorf("%w: ed25519 public key must be %d bytes", ErrInvalidVerification, ed25519.PublicKeySize)
+		}
+		s.verification.publicKey = publicKey
+		s.verification.signature = nil
+		s.verification.signatureURL = true
+		return nil
+	}
+}
+
+// SHA256 pins the SHA-256 digest of the URL spec
+func (b *Builder) SHA256(digest string) *Builder {
+	b.options = append(b.options, WithSHA256(digest))
+	return b
+}
+
+// Signature verifies the URL spec against a detached ed25519 signature
+func (b *Builder) Signature(publicKey ed25519.PublicKey, signature []byte) *Builder {
+	b.options = append(b.options, WithSignature(publicKey, signature))
+	return b
+}
+
+// SignatureURL verifies the URL spec against the signature published next to it
+func (b *Builder) SignatureURL(publicKey ed25519.PublicKey) *Builder {
+	b.options = append(b.options, WithSignatureURL(publicKey))
+	return b
+}
+
+// verifySpec checks fetched content before it is parsed
+func (s *Scalar) verifySpec(ctx context.Context, content []byte) error {
+	v := s.verification
+	if v.digest != nil {
+		digest := sha256.Sum256(content)
+		if !bytes.Equal(digest[:], v.digest) {
+			return fmt.Errorf("%w: SHA-256 digest %x does not match the pinned digest", ErrVerificationFailed, digest)
+		}
+	}
+
+	if v.publicKey == nil {
+		return nil
+	}
+
+	signature := v.signature
+	if v.signatureURL {
+		var err error
+		if signature, err = s.fetchSignature(ctx); err != nil {
+			return fmt.Errorf("%w: %w", ErrVerificationFailed, err)
+		}
+	}
+	if !ed25519.Verify(v.publicKey, content, signature) {
+		return fmt.Errorf("%w: ed25519 signature does not match", ErrVerificationFailed)
+	}
+	return nil
+}
+
+// fetchSignature fetches the signature published next to the spec
+func (s *Scalar) fetchSignature(ctx context.Context) ([]byte, error) {
+	signatureURL, err := url.Parse(s.specURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+	signatureURL.Path += signatureSuffix
+	if signatureURL.RawPath != "" {
+		signatureURL.RawPath += signatureSuffix
+	}
+
+	response, err := s.urlSource(signatureURL.String()).fetch(ctx, urlValidators{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signature: %w", err)
+	}
+	return decodeSignature(response.content)
+}
+
+// decodeSignature decodes a raw or base64-encoded ed25519 signature
+func decodeSignature(data []byte) ([]byte, error) {
+	if len(data) == ed25519.SignatureSize {
+		return data, nil
+	}
+
+	text := strings.TrimSpace(string(data))
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if signature, err := encoding.DecodeString(text); err == nil && len(signature) == ed25519.SignatureSize {
+			return signature, nil
+		}
+	}
+	return nil, errors.New("signature must be a raw or base64-encoded ed25519 signature")
+}
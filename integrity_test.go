@@ -0,0 +1,169 @@
+package goscalar
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	publishedSpec = `{"openapi": "3.0.0", "info": {"title": "Published API"}}`
+	tamperedSpec  = `{"openapi": "3.0.0", "info": {"title": "Tampered API"}}`
+)
+
+func Test_SpecVerification(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	otherKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	signature := ed25519.Sign(privateKey, []byte(publishedSpec))
+	digest := sha256.Sum256([]byte(publishedSpec))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/openapi.json", "/raw.json", "/unsigned.json":
+			w.Write([]byte(publishedSpec))
+		case "/openapi.json.sig":
+			w.Write([]byte(base64.StdEncoding.EncodeToString(signature) + "\n"))
+		case "/raw.json.sig":
+			w.Write(signature)
+		case "/tampered.json":
+			w.Write([]byte(tamperedSpec))
+		case "/tampered.json.sig":
+			w.Write([]byte(base64.StdEncoding.EncodeToString(signature)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		options     []Option
+		expectedErr error
+	}{
+		{name: "pinned digest", path: "/openapi.json", options: []Option{WithSHA256(hex.EncodeToString(digest[:]))}},
+		{name: "prefixed digest", path: "/openapi.json", options: []Option{WithSHA256("sha256:" + hex.EncodeToString(digest[:]))}},
+		{name: "digest mismatch", path: "/tampered.json", options: []Option{WithSHA256(hex.EncodeToString(digest[:]))}, expectedErr: ErrVerificationFailed},
+		{name: "detached signature", path: "/openapi.json", options: []Option{WithSignature(publicKey, signature)}},
+		{name: "signature of another key", path: "/openapi.json", options: []Option{WithSignature(otherKey, signature)}, expectedErr: ErrVerificationFailed},
+		{name: "base64 signature URL", path: "/openapi.json", options: []Option{WithSignatureURL(publicKey)}},
+		{name: "raw signature URL", path: "/raw.json", options: []Option{WithSignatureURL(publicKey)}},
+		{name: "tampered spec", path: "/tampered.json", options: []Option{WithSignatureURL(publicKey)}, expectedErr: ErrVerificationFailed},
+		{name: "missing signature", path: "/unsigned.json", options: []Option{WithSignatureURL(publicKey)}, expectedErr: ErrVerificationFailed},
+		{
+			name:    "digest and signature",
+			path:    "/openapi.json",
+			options: []Option{WithSHA256(hex.EncodeToString(digest[:])), WithSignatureURL(publicKey)},
+		},
+		{name: "invalid digest", path: "/openapi.json", options: []Option{WithSHA256("abc")}, expectedErr: ErrInvalidVerification},
+		{name: "invalid public key", path: "/openapi.json", options: []Option{WithSignatureURL(publicKey[:16])}, expectedErr: ErrInvalidVerification},
+		{name: "invalid signature", path: "/openapi.json", options: []Option{WithSignature(publicKey, signature[:10])}, expectedErr: ErrInvalidVerification},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scalar, err := FromURL(server.URL+tt.path, tt.options...)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				require.Nil(t, scalar)
+				return
+			}
+
+			require.NoError(t, err)
+			defer scalar.Close()
+			require.Contains(t, serveSpec(t, scalar), "Published API")
+		})
+	}
+
+	t.Run("requires a URL", func(t *testing.T) {
+		_, err := FromContent(publishedSpec, WithSignature(publicKey, signature))
+		require.ErrorIs(t, err, ErrVerifyRequiresURL)
+
+		_, err = NewScalar(WithSource("Docs", "docs", WithSpecContent(publishedSpec), WithSHA256(hex.EncodeToString(digest[:]))))
+		require.ErrorIs(t, err, ErrVerifyRequiresURL)
+	})
+
+	t.Run("sources", func(t *testing.T) {
+		_, err := NewScalar(WithSource("Docs", "docs", WithURL(server.URL+"/tampered.json"), WithSignatureURL(publicKey)))
+		require.ErrorIs(t, err, ErrVerificationFailed)
+	})
+
+	t.Run("builder", func(t *testing.T) {
+		scalar, err := NewBuilder().
+			URL(server.URL+"/openapi.json").
+			SHA256(hex.EncodeToString(digest[:])).
+			Signature(publicKey, signature).
+			Build()
+		require.NoError(t, err)
+		defer scalar.Close()
+
+		_, err = NewBuilder().URL(server.URL + "/tampered.json").SignatureURL(publicKey).Build()
+		require.ErrorIs(t, err, ErrVerificationFailed)
+	})
+}
+
+func Test_RefreshVerification(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	content := publishedSpec
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path == "/openapi.json.sig" {
+			// The signature stays the one of the published spec
+			w.Write(ed25519.Sign(privateKey, []byte(publishedSpec)))
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	events := make(chan ReloadEvent, 10)
+	scalar, err := FromURL(server.URL+"/openapi.json",
+		WithSignatureURL(publicKey),
+		WithRefresh(20*time.Millisecond),
+		WithReloadCallback(func(event ReloadEvent) {
+			select {
+			case events <- event:
+			default:
+			}
+		}),
+	)
+	require.NoError(t, err)
+	defer scalar.Close()
+
+	mu.Lock()
+	content = tamperedSpec
+	mu.Unlock()
+
+	event := waitReloadEvent(t, events, func(event ReloadEvent) bool { return event.Err != nil })
+	require.ErrorIs(t, event.Err, ErrVerificationFailed)
+	require.Contains(t, serveSpec(t, scalar), "Published API")
+	require.True(t, scalar.RefreshStatus().Stale())
+}
+
+func Test_RefreshVerificationAtStartup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(tamperedSpec))
+	}))
+	defer server.Close()
+
+	digest := sha256.Sum256([]byte(publishedSpec))
+	scalar, err := FromURL(server.URL, WithSHA256(hex.EncodeToString(digest[:])), WithRefresh(time.Minute))
+	require.ErrorIs(t, err, ErrVerificationFailed)
+	require.Nil(t, scalar)
+}
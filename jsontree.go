@@ -0,0 +1,229 @@
+package goscalar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// object is a decoded JSON object keeping the order of its keys, so specs
+// rewritten by the package keep the order their authors chose.
+// Values are *object, []any, string, json.Number, bool or nil.
+type object struct {
+	keys   []string
+	values map[string]any
+}
+
+// newObject creates an empty object
+func newObject() *object {
+	return &object{values: map[string]any{}}
+}
+
+// get returns the value of a key, nil objects have no keys
+func (o *object) get(key string) (any, bool) {
+	if o == nil {
+		return nil, false
+	}
+	value, ok := o.values[key]
+	return value, ok
+}
+
+// set sets the value of a key, new keys are appended
+func (o *object) set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// delete removes a key
+func (o *object) delete(key string) {
+	if _, ok := o.values[key]; !ok {
+		return
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i:i], o.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+// child returns the object stored under a key, or nil
+func (o *object) child(key string) *object {
+	value, _ := o.get(key)
+	child, _ := value.(*object)
+	return child
+}
+
+// ensureChild returns the object stored under a key, creating it when missing
+func (o *object) ensureChild(key string) *object {
+	if child := o.child(key); child != nil {
+		return child
+	}
+	child := newObject()
+	o.set(key, child)
+	return child
+}
+
+// str returns the string stored under a key, or ""
+func (o *object) str(key string) string {
+	value, _ := o.get(key)
+	str, _ := value.(string)
+	return str
+}
+
+// MarshalJSON encodes the object with its keys in order
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := writeJSONValue(&buf, key); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(':')
+		if err := writeJSONValue(&buf, o.values[key]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// decodeTree decodes a JSON document into ordered objects
+func decodeTree(content string) (any, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+	return decodeTreeValue(decoder)
+}
+
+// decodeTreeValue reads the next JSON value from the decoder
+func decodeTreeValue(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+
+	if delim == '[' {
+		items := []any{}
+		for decoder.More() {
+			item, err := decodeTreeValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		_, err := decoder.Token()
+		return items, err
+	}
+
+	obj := newObject()
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeTreeValue(decoder)
+		if err != nil {
+			return nil, err
+		}
+		obj.set(key.(string), value)
+	}
+	_, err = decoder.Token()
+	return obj, err
+}
+
+// encodeTree encodes a decoded tree without HTML escaping
+func encodeTree(value any) (string, error) {
+	var buf bytes.Buffer
+	if err := writeJSONValue(&buf, value); err != nil {
+		return "", fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// cloneTree returns a deep copy of a decoded tree
+func cloneTree(value any) any {
+	switch v := value.(type) {
+	case *object:
+		clone := &object{keys: append([]string(nil), v.keys...), values: make(map[string]any, len(v.values))}
+		for key, item := range v.values {
+			clone.values[key] = cloneTree(item)
+		}
+		return clone
+	case []any:
+		clone := make([]any, len(v))
+		for i, item := range v {
+			clone[i] = cloneTree(item)
+		}
+		return clone
+	default:
+		return value
+	}
+}
+
+// equalTree compares two decoded trees, ignoring the order of object keys
+func equalTree(a, b any) bool {
+	switch av := a.(type) {
+	case *object:
+		bv, ok := b.(*object)
+		if !ok || len(av.values) != len(bv.values) {
+			return false
+		}
+		for key, item := range av.values {
+			other, ok := bv.values[key]
+			if !ok || !equalTree(item, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equalTree(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+// walkTree calls fn for every object of a decoded tree, parents first
+func walkTree(value any, fn func(obj *object)) {
+	switch v := value.(type) {
+	case *object:
+		fn(v)
+		for _, key := range v.keys {
+			walkTree(v.values[key], fn)
+		}
+	case []any:
+		for _, item := range v {
+			walkTree(item, fn)
+		}
+	}
+}
+
+// escapePointer escapes a JSON pointer reference token (RFC 6901)
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// unescapePointer unescapes a JSON pointer reference token (RFC 6901)
+func unescapePointer(token string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+}
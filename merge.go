@@ -0,0 +1,726 @@
+package goscalar
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Merge conflict kinds
+	MergeConflictPath        = "path"
+	MergeConflictOperationID = "operationId"
+	MergeConflictTag         = "tag"
+	MergeConflictServer      = "server"
+
+	// mergedVersion is the info.version of merged specs
+	mergedVersion = "1.0.0"
+)
+
+var (
+	// serviceNamePattern matches service names, they are used in component names
+	serviceNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+	// Component sections, by spec family
+	openAPIComponentSections = [][]string{
+		{"components", "schemas"},
+		{"components", "responses"},
+		{"components", "parameters"},
+		{"components", "examples"},
+		{"components", "requestBodies"},
+		{"components", "headers"},
+		{"components", "securitySchemes"},
+		{"components", "links"},
+		{"components", "callbacks"},
+		{"components", "pathItems"},
+	}
+	swaggerComponentSections = [][]string{
+		{"definitions"},
+		{"parameters"},
+		{"responses"},
+		{"securityDefinitions"},
+	}
+
+	// Operation defaults declared once per spec, by spec family
+	openAPIOperationDefaults = []string{"security"}
+	swaggerOperationDefaults = []string{"security", "consumes", "produces"}
+
+	// Swagger 2 fields describing the target server
+	swaggerServerFields = []string{"host", "basePath", "schemes"}
+
+	// httpMethods are the path item keys holding operations
+	httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+	// Errors
+	ErrInvalidMerge = errors.New("specs cannot be merged")
+)
+
+// ServiceSpec is a service spec combined by MergeSpecs
+type ServiceSpec struct {
+	Name       string // Service name, colliding components are renamed to "<name>.<component>"
+	PathPrefix string // Prepended to every path of the service, e.g. "/orders"
+	Content    string // JSON or YAML specification
+}
+
+// MergeService is a service spec combined by WithMerge
+type MergeService struct {
+	Name       string   // Service name, colliding components are renamed to "<name>.<component>"
+	PathPrefix string   // Prepended to every path of the service, e.g. "/orders"
+	Options    []Option // Loading options, e.g. WithURL
+}
+
+// MergeWarning describes a definition MergeSpecs could not merge. The definition of
+// the earlier service is kept and the one of the reported service is dropped.
+type MergeWarning struct {
+	Kind     string // One of the MergeConflict* kinds
+	Service  string // Service whose definition was dropped
+	Location string // JSON pointer of the definition in the merged spec, e.g. /paths/~1orders/get
+	Message  string
+}
+
+// String returns a readable description of the warning
+func (w MergeWarning) String() string {
+	return fmt.Sprintf("%s conflict at %s from service %q: %s", w.Kind, w.Location, w.Service, w.Message)
+}
+
+// MergeResult is the outcome of MergeSpecs
+type MergeResult struct {
+	Content  string // Merged JSON specification
+	Warnings []MergeWarning
+}
+
+// MergeSpecs combines several OpenAPI 3 or several Swagger 2 specs into one. Paths are
+// prefixed per service, colliding components that are not identical are renamed to
+// "<service>.<name>" and the matching $refs rewritten. Tags are merged, servers declared by
+// every service stay at the root while the others are set on the paths of their service,
+// and security requirements that differ between services are moved onto their operations.
+// Conflicts that cannot be resolved keep the first definition and are reported as warnings.
+func MergeSpecs(title string, services ...ServiceSpec) (*MergeResult, error) {
+	if len(services) == 0 {
+		return nil, fmt.Errorf("%w: no services", ErrInvalidMerge)
+	}
+
+	m := &merger{merged: newObject()}
+	for _, service := range services {
+		if err := m.add(service); err != nil {
+			return nil, err
+		}
+	}
+
+	content, err := m.finish(title)
+	if err != nil {
+		return nil, err
+	}
+	return &MergeResult{Content: content, Warnings: m.warnings}, nil
+}
+
+// WithMerge loads several service specs, e.g. with WithURL, and serves them merged into
+// one with MergeSpecs, titled after the documentation title. The services are loaded once
+// every option is applied, so WithTitle, WithHTTPClient and WithFetchPolicy may come in
+// any position. Unresolved conflicts are available from MergeWarnings.
+func WithMerge(services ...MergeService) Option {
+	return func(s *Scalar) error {
+		s.mergeServices = services
+		s.mergePending = true
+		s.urlPending = false
+		return nil
+	}
+}
+
+// loadMerge loads the WithMerge services and merges them
+func (s *Scalar) loadMerge() error {
+	if !s.mergePending {
+		return nil
+	}
+	s.mergePending = false
+
+	specs := make([]ServiceSpec, 0, len(s.mergeServices))
+	for _, service := range s.mergeServices {
+		content, err := loadSource(s, service.Options)
+		if err != nil {
+			return fmt.Errorf("failed to load service %q: %w", service.Name, err)
+		}
+		specs = append(specs, ServiceSpec{Name: service.Name, PathPrefix: service.PathPrefix, Content: content})
+	}
+
+	title := s.config.Title
+	if title == "" {
+		title = defaultTitle
+	}
+	result, err := MergeSpecs(title, specs...)
+	if err != nil {
+		return err
+	}
+
+	s.config.Content = result.Content
+	s.mergeWarnings = result.Warnings
+	return nil
+}
+
+// Merge serves several service specs merged into one
+func (b *Builder) Merge(services ...MergeService) *Builder {
+	b.options = append(b.options, WithMerge(services...))
+	return b
+}
+
+// MergeWarnings returns the conflicts WithMerge could not resolve
+func (s *Scalar) MergeWarnings() []MergeWarning {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+	return s.mergeWarnings
+}
+
+// mergeService is a parsed service spec
+type mergeService struct {
+	name     string
+	prefix   string
+	root     *object
+	swagger  bool
+	renames  map[string]map[string]string // Renamed components, by section pointer and name
+	defaults map[string]any               // Operation defaults declared by the spec
+	servers  []any                        // OpenAPI 3 servers set on the path items, when not shared
+}
+
+// merger accumulates service specs into one
+type merger struct {
+	merged   *object
+	services []*mergeService
+	warnings []MergeWarning
+	tags     map[string]*object
+	opIDs    map[string]string
+	servers  []any // OpenAPI 3 servers shared by every service, kept at the root
+}
+
+// add parses a service spec and merges its components
+func (m *merger) add(service ServiceSpec) error {
+	if !serviceNamePattern.MatchString(service.Name) {
+		return fmt.Errorf("%w: service name %q must be letters, digits, dots, dashes and underscores", ErrInvalidMerge, service.Name)
+	}
+	for _, other := range m.services {
+		if other.name == service.Name {
+			return fmt.Errorf("%w: duplicate service name %q", ErrInvalidMerge, service.Name)
+		}
+	}
+
+	content, err := parseSpecContent(service.Content)
+	if err != nil {
+		return fmt.Errorf("failed to parse service %q: %w", service.Name, err)
+	}
+	tree, err := decodeTree(content)
+	if err != nil {
+		return fmt.Errorf("failed to parse service %q: %w", service.Name, err)
+	}
+	root, ok := tree.(*object)
+	if !ok {
+		return fmt.Errorf("%w: service %q is not an object", ErrInvalidMerge, service.Name)
+	}
+
+	ms := &mergeService{
+		name:    service.Name,
+		prefix:  normalizePrefix(service.PathPrefix),
+		root:    root,
+		swagger: root.str("swagger") != "",
+	}
+	if !ms.swagger && root.str("openapi") == "" {
+		return fmt.Errorf("%w: service %q has neither an openapi nor a swagger version", ErrInvalidMerge, service.Name)
+	}
+	if len(m.services) > 0 && m.services[0].swagger != ms.swagger {
+		return fmt.Errorf("%w: service %q mixes OpenAPI 3 and Swagger 2", ErrInvalidMerge, service.Name)
+	}
+
+	ms.defaults = map[string]any{}
+	for _, key := range m.operationDefaults(ms.swagger) {
+		if value, ok := root.get(key); ok {
+			ms.defaults[key] = value
+		}
+	}
+
+	if err := m.renameComponents(ms); err != nil {
+		return fmt.Errorf("failed to merge service %q: %w", service.Name, err)
+	}
+	if err := rewriteRefs(root, ms.renames); err != nil {
+		return fmt.Errorf("failed to merge service %q: %w", service.Name, err)
+	}
+	m.mergeComponents(ms)
+	m.services = append(m.services, ms)
+	return nil
+}
+
+// operationDefaults returns the operation defaults of a spec family
+func (m *merger) operationDefaults(swagger bool) []string {
+	if swagger {
+		return swaggerOperationDefaults
+	}
+	return openAPIOperationDefaults
+}
+
+// componentSections returns the component sections of a spec family
+func componentSections(swagger bool) [][]string {
+	if swagger {
+		return swaggerComponentSections
+	}
+	return openAPIComponentSections
+}
+
+// sectionObject returns the object holding a component section, or nil
+func sectionObject(root *object, section []string) *object {
+	for _, key := range section {
+		if root = root.child(key); root == nil {
+			return nil
+		}
+	}
+	return root
+}
+
+// sectionPointer returns the JSON pointer of a component section
+func sectionPointer(section []string) string {
+	return "/" + strings.Join(section, "/")
+}
+
+// renameComponents decides which components of a service must be renamed. A component
+// colliding with a different one is renamed, and so is any component that only matched
+// its counterpart before the components it references were renamed.
+func (m *merger) renameComponents(ms *mergeService) error {
+	ms.renames = map[string]map[string]string{}
+
+	for changed := true; changed; {
+		changed = false
+		for _, section := range componentSections(ms.swagger) {
+			components := sectionObject(ms.root, section)
+			existing := sectionObject(m.merged, section)
+			if components == nil || existing == nil {
+				continue
+			}
+
+			pointer := sectionPointer(section)
+			for _, name := range components.keys {
+				if _, renamed := ms.renames[pointer][name]; renamed {
+					continue
+				}
+				current, ok := existing.get(name)
+				if !ok {
+					continue
+				}
+
+				candidate := cloneTree(components.values[name])
+				if err := rewriteRefs(candidate, ms.renames); err != nil {
+					return err
+				}
+				if equalTree(candidate, current) {
+					continue
+				}
+
+				if ms.renames[pointer] == nil {
+					ms.renames[pointer] = map[string]string{}
+				}
+				ms.renames[pointer][name] = availableName(ms.name+"."+name, existing, components, ms.renames[pointer])
+				changed = true
+			}
+		}
+	}
+	return nil
+}
+
+// availableName returns the name, suffixed with a number when it is already taken
+func availableName(name string, existing, components *object, renames map[string]string) string {
+	taken := func(candidate string) bool {
+		if _, ok := existing.get(candidate); ok {
+			return true
+		}
+		if _, ok := components.get(candidate); ok {
+			return true
+		}
+		for _, renamed := range renames {
+			if renamed == candidate {
+				return true
+			}
+		}
+		return false
+	}
+
+	candidate := name
+	for i := 2; taken(candidate); i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+	return candidate
+}
+
+// mergeComponents adds the components of a service, identical ones are shared
+func (m *merger) mergeComponents(ms *mergeService) {
+	for _, section := range componentSections(ms.swagger) {
+		components := sectionObject(ms.root, section)
+		if components == nil {
+			continue
+		}
+
+		target := m.merged
+		for _, key := range section {
+			target = target.ensureChild(key)
+		}
+
+		pointer := sectionPointer(section)
+		for _, name := range components.keys {
+			if renamed, ok := ms.renames[pointer][name]; ok {
+				target.set(renamed, components.values[name])
+			} else if _, ok := target.get(name); !ok {
+				target.set(name, components.values[name])
+			}
+		}
+	}
+}
+
+// rewriteRefs rewrites the references to renamed components: $refs, discriminator
+// mappings and the keys of security requirements
+func rewriteRefs(tree any, renames map[string]map[string]string) error {
+	if len(renames) == 0 {
+		return nil
+	}
+
+	securityRenames := renames["/components/securitySchemes"]
+	if securityRenames == nil {
+		securityRenames = renames["/securityDefinitions"]
+	}
+	schemaRenames := renames["/components/schemas"]
+	if schemaRenames == nil {
+		schemaRenames = renames["/definitions"]
+	}
+
+	var err error
+	walkTree(tree, func(obj *object) {
+		if ref, ok := obj.values["$ref"].(string); ok {
+			obj.values["$ref"] = renameRef(ref, renames)
+		}
+
+		if mapping := obj.child("discriminator").child("mapping"); mapping != nil {
+			for _, key := range mapping.keys {
+				target, _ := mapping.values[key].(string)
+				if renamed, ok := schemaRenames[target]; ok {
+					mapping.values[key] = renamed
+				} else if strings.HasPrefix(target, "#/") {
+					mapping.values[key] = renameRef(target, renames)
+				}
+			}
+		}
+
+		if requirements, ok := obj.values["security"].([]any); ok && securityRenames != nil {
+			for _, requirement := range requirements {
+				if renameErr := renameKeys(requirement, securityRenames); renameErr != nil && err == nil {
+					err = renameErr
+				}
+			}
+		}
+	})
+	return err
+}
+
+// renameRef rewrites a local reference to a renamed component
+func renameRef(ref string, renames map[string]map[string]string) string {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return ref
+	}
+
+	for section, names := range renames {
+		rest, ok := strings.CutPrefix(pointer, section+"/")
+		if !ok {
+			continue
+		}
+		name, tail, _ := strings.Cut(rest, "/")
+		renamed, ok := names[unescapePointer(name)]
+		if !ok {
+			return ref
+		}
+		ref = "#" + section + "/" + escapePointer(renamed)
+		if tail != "" {
+			ref += "/" + tail
+		}
+		return ref
+	}
+	return ref
+}
+
+// renameKeys renames the keys of a security requirement object, refusing to
+// rename a key onto one the requirement already holds
+func renameKeys(value any, renames map[string]string) error {
+	requirement, ok := value.(*object)
+	if !ok {
+		return nil
+	}
+	for _, key := range requirement.keys {
+		renamed, ok := renames[key]
+		if !ok {
+			continue
+		}
+		if _, taken := requirement.get(renamed); taken {
+			return fmt.Errorf("%w: security requirement %q cannot be renamed to %q, which it already uses", ErrInvalidMerge, key, renamed)
+		}
+	}
+	for i, key := range requirement.keys {
+		if renamed, ok := renames[key]; ok {
+			requirement.keys[i] = renamed
+			requirement.values[renamed] = requirement.values[key]
+			delete(requirement.values, key)
+		}
+	}
+	return nil
+}
+
+// finish merges the paths, tags and servers and encodes the merged spec
+func (m *merger) finish(title string) (string, error) {
+	first := m.services[0]
+
+	merged := newObject()
+	if first.swagger {
+		merged.set("swagger", first.root.values["swagger"])
+	} else {
+		merged.set("openapi", first.root.values["openapi"])
+	}
+	info := newObject()
+	info.set("title", title)
+	info.set("version", mergedVersion)
+	merged.set("info", info)
+
+	if first.swagger {
+		m.mergeSwaggerServers(merged)
+	} else {
+		m.mergeServers(merged)
+	}
+
+	// Operation defaults stay global when every service agrees, otherwise they
+	// are moved onto the operations of the services declaring them
+	pushDown := map[string]bool{}
+	for _, key := range m.operationDefaults(first.swagger) {
+		value, ok := first.defaults[key]
+		for _, ms := range m.services[1:] {
+			other, otherOK := ms.defaults[key]
+			if ok != otherOK || !equalTree(value, other) {
+				pushDown[key] = true
+			}
+		}
+		if ok && !pushDown[key] {
+			merged.set(key, value)
+		}
+	}
+
+	m.tags = map[string]*object{}
+	m.opIDs = map[string]string{}
+	tags := []any{}
+	paths := newObject()
+	webhooks := newObject()
+	for _, ms := range m.services {
+		tags = m.mergeTags(ms, tags)
+		m.mergePathItems(ms, paths, "paths", ms.prefix, pushDown)
+		m.mergePathItems(ms, webhooks, "webhooks", "", pushDown)
+	}
+
+	if len(tags) > 0 {
+		merged.set("tags", tags)
+	}
+	merged.set("paths", paths)
+	if len(webhooks.keys) > 0 {
+		merged.set("webhooks", webhooks)
+	}
+	for _, key := range m.merged.keys {
+		merged.set(key, m.merged.values[key])
+	}
+
+	return encodeTree(merged)
+}
+
+// mergeServers keeps the OpenAPI 3 servers declared by every service at the root.
+// Services declaring other servers get them on their path items, so their operations
+// are never sent to the host of another service.
+func (m *merger) mergeServers(merged *object) {
+	m.servers = []any{}
+	first, _ := m.services[0].root.values["servers"].([]any)
+	for _, server := range first {
+		shared := true
+		for _, ms := range m.services[1:] {
+			list, _ := ms.root.values["servers"].([]any)
+			if !containsTree(list, server) {
+				shared = false
+				break
+			}
+		}
+		if shared && !containsTree(m.servers, server) {
+			m.servers = append(m.servers, server)
+		}
+	}
+
+	for _, ms := range m.services {
+		list, _ := ms.root.values["servers"].([]any)
+		if len(list) != len(m.servers) {
+			ms.servers = list
+		}
+	}
+
+	if len(m.servers) > 0 {
+		merged.set("servers", m.servers)
+	}
+}
+
+// containsTree reports whether a list holds a value equal to the given one
+func containsTree(list []any, value any) bool {
+	for _, item := range list {
+		if equalTree(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeSwaggerServers keeps the Swagger 2 host, base path and schemes of the first service declaring them
+func (m *merger) mergeSwaggerServers(merged *object) {
+	for _, field := range swaggerServerFields {
+		for _, ms := range m.services {
+			value, ok := ms.root.get(field)
+			if !ok {
+				continue
+			}
+			existing, ok := merged.get(field)
+			if !ok {
+				merged.set(field, value)
+				continue
+			}
+			if !equalTree(existing, value) {
+				m.warn(MergeConflictServer, ms.name, "/"+field, fmt.Sprintf("%s differs from the one of an earlier service", field))
+			}
+		}
+	}
+}
+
+// mergeTags merges the tags of a service by name
+func (m *merger) mergeTags(ms *mergeService, tags []any) []any {
+	list, _ := ms.root.values["tags"].([]any)
+	for _, item := range list {
+		tag, ok := item.(*object)
+		if !ok {
+			continue
+		}
+		name := tag.str("name")
+		existing, ok := m.tags[name]
+		if !ok {
+			m.tags[name] = tag
+			tags = append(tags, tag)
+			continue
+		}
+		if !equalTree(existing, tag) {
+			m.warn(MergeConflictTag, ms.name, "/tags", fmt.Sprintf("tag %q is declared differently", name))
+		}
+	}
+	return tags
+}
+
+// mergePathItems merges the path items of a service, operation by operation
+func (m *merger) mergePathItems(ms *mergeService, target *object, key, prefix string, pushDown map[string]bool) {
+	items := ms.root.child(key)
+	if items == nil {
+		return
+	}
+
+	for _, path := range items.keys {
+		item, ok := items.values[path].(*object)
+		if !ok {
+			continue
+		}
+		mergedPath := path
+		if prefix != "" {
+			mergedPath = prefix + path
+		}
+		location := "/" + key + "/" + escapePointer(mergedPath)
+		m.applyDefaults(ms, item, location, pushDown)
+		if _, ok := item.get("servers"); !ok && ms.servers != nil && key == "paths" {
+			item.set("servers", cloneTree(ms.servers))
+		}
+
+		existing := target.child(mergedPath)
+		if existing == nil {
+			target.set(mergedPath, item)
+			continue
+		}
+
+		// Operations joining the path item of another service keep their own servers
+		if servers := m.pathServers(item); key == "paths" && !equalTree(servers, m.pathServers(existing)) {
+			for _, method := range httpMethods {
+				operation := item.child(method)
+				if operation == nil {
+					continue
+				}
+				if _, ok := operation.get("servers"); !ok {
+					operation.set("servers", cloneTree(servers))
+				}
+			}
+			item.delete("servers")
+		}
+
+		for _, field := range item.keys {
+			value := item.values[field]
+			current, ok := existing.get(field)
+			switch {
+			case !ok:
+				existing.set(field, value)
+			case isHTTPMethod(field):
+				m.warn(MergeConflictPath, ms.name, location+"/"+field, fmt.Sprintf("operation %s %s is already defined", strings.ToUpper(field), mergedPath))
+			case !equalTree(current, value):
+				m.warn(MergeConflictPath, ms.name, location+"/"+field, fmt.Sprintf("%s of %s is declared differently", field, mergedPath))
+			}
+		}
+	}
+}
+
+// pathServers returns the servers targeted by the operations of a merged path item
+func (m *merger) pathServers(item *object) any {
+	if servers, ok := item.get("servers"); ok {
+		return servers
+	}
+	return m.servers
+}
+
+// applyDefaults moves the operation defaults of a service onto its operations
+// and reports duplicate operation ids
+func (m *merger) applyDefaults(ms *mergeService, item *object, location string, pushDown map[string]bool) {
+	for _, method := range httpMethods {
+		operation := item.child(method)
+		if operation == nil {
+			continue
+		}
+
+		for _, key := range m.operationDefaults(ms.swagger) {
+			if _, ok := operation.get(key); ok || !pushDown[key] {
+				continue
+			}
+			if value, ok := ms.defaults[key]; ok {
+				operation.set(key, cloneTree(value))
+			}
+		}
+
+		id := operation.str("operationId")
+		if id == "" {
+			continue
+		}
+		if other, ok := m.opIDs[id]; ok {
+			if other != ms.name {
+				m.warn(MergeConflictOperationID, ms.name, location+"/"+method+"/operationId", fmt.Sprintf("operationId %q is also used by service %q", id, other))
+			}
+			continue
+		}
+		m.opIDs[id] = ms.name
+	}
+}
+
+// warn records a merge warning
+func (m *merger) warn(kind, service, location, message string) {
+	m.warnings = append(m.warnings, MergeWarning{Kind: kind, Service: service, Location: location, Message: message})
+}
+
+// isHTTPMethod reports whether a path item key holds an operation
+func isHTTPMethod(key string) bool {
+	for _, method := range httpMethods {
+		if key == method {
+			return true
+		}
+	}
+	return false
+}
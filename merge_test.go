@@ -0,0 +1,428 @@
+package goscalar
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	ordersSpec = `{
+  "openapi": "3.0.3",
+  "info": {"title": "Orders", "version": "2.1.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "security": [{"apiKey": []}],
+  "tags": [{"name": "orders", "description": "Order management"}, {"name": "shared"}],
+  "paths": {
+    "/items": {"get": {"operationId": "listItems", "tags": ["orders"], "responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}}}}},
+    "/health": {"get": {"operationId": "health", "responses": {"200": {"description": "OK"}}}}
+  },
+  "components": {
+    "schemas": {
+      "User": {"type": "object", "properties": {"id": {"type": "integer"}}},
+      "Error": {"type": "object", "properties": {"message": {"type": "string"}}},
+      "Invoice": {"type": "object", "properties": {"owner": {"$ref": "#/components/schemas/User"}}}
+    },
+    "securitySchemes": {
+      "apiKey": {"type": "apiKey", "in": "header", "name": "X-Orders-Key"}
+    }
+  }
+}`
+
+	billingSpec = `
+openapi: 3.0.3
+info:
+  title: Billing
+  version: 1.0.0
+servers:
+  - url: https://api.example.com
+  - url: https://billing.example.com
+security:
+  - apiKey: []
+tags:
+  - name: billing
+  - name: shared
+    description: Declared differently
+paths:
+  /items:
+    get:
+      operationId: listItems
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Invoice"
+  /pets:
+    get:
+      operationId: listPets
+      security: []
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                oneOf:
+                  - $ref: "#/components/schemas/User"
+                discriminator:
+                  propertyName: kind
+                  mapping:
+                    user: "#/components/schemas/User"
+                    other: User
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        name:
+          type: string
+    Error:
+      type: object
+      properties:
+        message:
+          type: string
+    Invoice:
+      type: object
+      properties:
+        owner:
+          $ref: "#/components/schemas/User"
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      in: header
+      name: X-Billing-Key
+`
+)
+
+func Test_MergeSpecs(t *testing.T) {
+	result, err := MergeSpecs("All APIs",
+		ServiceSpec{Name: "orders", PathPrefix: "/orders", Content: ordersSpec},
+		ServiceSpec{Name: "billing", PathPrefix: "billing/", Content: billingSpec},
+		ServiceSpec{Name: "health", Content: `{"openapi": "3.0.3", "info": {"title": "Health", "version": "1"}, "paths": {"/orders/health": {"get": {"operationId": "health", "responses": {"204": {"description": "No Content"}}}}}}`},
+	)
+	require.NoError(t, err)
+
+	var merged map[string]any
+	require.NoError(t, json.Unmarshal([]byte(result.Content), &merged))
+	lookup := func(path ...string) any {
+		var value any = merged
+		for _, key := range path {
+			object, ok := value.(map[string]any)
+			require.True(t, ok, "missing %v", path)
+			value = object[key]
+		}
+		return value
+	}
+
+	t.Run("info", func(t *testing.T) {
+		require.Equal(t, "3.0.3", merged["openapi"])
+		require.Equal(t, "All APIs", lookup("info", "title"))
+	})
+
+	t.Run("paths are prefixed in order", func(t *testing.T) {
+		require.Contains(t, result.Content, `"paths":{"/orders/items":`)
+		require.NotNil(t, lookup("paths", "/orders/items", "get"))
+		require.NotNil(t, lookup("paths", "/billing/items", "get"))
+		require.NotNil(t, lookup("paths", "/billing/pets", "get"))
+	})
+
+	t.Run("colliding components are renamed", func(t *testing.T) {
+		schemas := lookup("components", "schemas").(map[string]any)
+		require.Len(t, schemas, 5)
+		require.Contains(t, schemas, "User")
+		require.Contains(t, schemas, "billing.User")
+		require.Contains(t, schemas, "Error")
+		require.NotContains(t, schemas, "billing.Error")
+
+		// Invoice only differs through the User it references
+		require.Equal(t, "#/components/schemas/User", lookup("components", "schemas", "Invoice", "properties", "owner", "$ref"))
+		require.Equal(t, "#/components/schemas/billing.User", lookup("components", "schemas", "billing.Invoice", "properties", "owner", "$ref"))
+	})
+
+	t.Run("refs are rewritten", func(t *testing.T) {
+		require.Equal(t, "#/components/schemas/User", lookup("paths", "/orders/items", "get", "responses", "200", "content", "application/json", "schema", "$ref"))
+		require.Equal(t, "#/components/schemas/billing.Invoice", lookup("paths", "/billing/items", "get", "responses", "200", "content", "application/json", "schema", "$ref"))
+
+		schema := lookup("paths", "/billing/pets", "get", "responses", "200", "content", "application/json", "schema").(map[string]any)
+		require.Equal(t, "#/components/schemas/billing.User", schema["oneOf"].([]any)[0].(map[string]any)["$ref"])
+		mapping := schema["discriminator"].(map[string]any)["mapping"].(map[string]any)
+		require.Equal(t, "#/components/schemas/billing.User", mapping["user"])
+		require.Equal(t, "billing.User", mapping["other"])
+	})
+
+	t.Run("differing security moves onto operations", func(t *testing.T) {
+		require.NotContains(t, merged, "security")
+		require.Contains(t, lookup("components", "securitySchemes").(map[string]any), "billing.apiKey")
+		require.Equal(t, []any{map[string]any{"apiKey": []any{}}}, lookup("paths", "/orders/items", "get", "security"))
+		require.Equal(t, []any{map[string]any{"billing.apiKey": []any{}}}, lookup("paths", "/billing/items", "get", "security"))
+		require.Equal(t, []any{}, lookup("paths", "/billing/pets", "get", "security"))
+		require.Equal(t, []any{map[string]any{"apiKey": []any{}}}, lookup("paths", "/orders/health", "get", "security"))
+	})
+
+	t.Run("tags are merged", func(t *testing.T) {
+		require.Len(t, merged["tags"], 3)
+	})
+
+	t.Run("servers stay with their service", func(t *testing.T) {
+		require.NotContains(t, merged, "servers")
+		require.Equal(t, []any{map[string]any{"url": "https://api.example.com"}}, lookup("paths", "/orders/items", "servers"))
+		require.Equal(t, []any{
+			map[string]any{"url": "https://api.example.com"},
+			map[string]any{"url": "https://billing.example.com"},
+		}, lookup("paths", "/billing/pets", "servers"))
+	})
+
+	t.Run("unresolved conflicts are reported", func(t *testing.T) {
+		require.Equal(t, []MergeWarning{
+			{
+				Kind:     MergeConflictTag,
+				Service:  "billing",
+				Location: "/tags",
+				Message:  `tag "shared" is declared differently`,
+			},
+			{
+				Kind:     MergeConflictOperationID,
+				Service:  "billing",
+				Location: "/paths/~1billing~1items/get/operationId",
+				Message:  `operationId "listItems" is also used by service "orders"`,
+			},
+			{
+				Kind:     MergeConflictOperationID,
+				Service:  "health",
+				Location: "/paths/~1orders~1health/get/operationId",
+				Message:  `operationId "health" is also used by service "orders"`,
+			},
+			{
+				Kind:     MergeConflictPath,
+				Service:  "health",
+				Location: "/paths/~1orders~1health/get",
+				Message:  "operation GET /orders/health is already defined",
+			},
+		}, result.Warnings)
+		require.Contains(t, lookup("paths", "/orders/health", "get", "responses"), "200")
+		require.Contains(t, result.Warnings[3].String(), `path conflict at /paths/~1orders~1health/get from service "health"`)
+	})
+}
+
+func Test_MergeServers(t *testing.T) {
+	spec := func(operation string, servers ...string) string {
+		list := make([]string, 0, len(servers))
+		for _, server := range servers {
+			list = append(list, `{"url": "`+server+`"}`)
+		}
+		return `{"openapi": "3.0.3", "info": {"title": "API", "version": "1"}, "servers": [` + strings.Join(list, ",") + `], "paths": {` + operation + `: {"responses": {"200": {"description": "OK"}}}}}}`
+	}
+
+	result, err := MergeSpecs("All",
+		ServiceSpec{Name: "orders", Content: spec(`"/orders": {"get"`, "https://gateway.example.com")},
+		ServiceSpec{Name: "billing", Content: spec(`"/billing": {"get"`, "https://gateway.example.com", "https://billing.example.com")},
+		ServiceSpec{Name: "billing-admin", Content: spec(`"/billing": {"post"`, "https://admin.example.com", "https://gateway.example.com")},
+	)
+	require.NoError(t, err)
+	require.Empty(t, result.Warnings)
+
+	var merged struct {
+		Servers []map[string]any `json:"servers"`
+		Paths   map[string]struct {
+			Servers []map[string]any `json:"servers"`
+			Get     struct {
+				Servers []map[string]any `json:"servers"`
+			} `json:"get"`
+			Post struct {
+				Servers []map[string]any `json:"servers"`
+			} `json:"post"`
+		} `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result.Content), &merged))
+
+	require.Equal(t, []map[string]any{{"url": "https://gateway.example.com"}}, merged.Servers)
+	require.Nil(t, merged.Paths["/orders"].Servers)
+	require.Equal(t, []map[string]any{
+		{"url": "https://gateway.example.com"},
+		{"url": "https://billing.example.com"},
+	}, merged.Paths["/billing"].Servers)
+	require.Nil(t, merged.Paths["/billing"].Get.Servers)
+
+	// Operations joining the path item of another service keep their own servers
+	require.Equal(t, []map[string]any{
+		{"url": "https://admin.example.com"},
+		{"url": "https://gateway.example.com"},
+	}, merged.Paths["/billing"].Post.Servers)
+}
+
+func Test_MergeSwaggerSpecs(t *testing.T) {
+	result, err := MergeSpecs("Legacy",
+		ServiceSpec{Name: "users", PathPrefix: "/users", Content: `{"swagger": "2.0", "info": {"title": "Users"}, "host": "users.example.com", "produces": ["application/json"], "paths": {"/": {"get": {"responses": {"200": {"description": "OK", "schema": {"$ref": "#/definitions/User"}}}}}}, "definitions": {"User": {"type": "object"}}}`},
+		ServiceSpec{Name: "admin", PathPrefix: "/admin", Content: `{"swagger": "2.0", "info": {"title": "Admin"}, "host": "admin.example.com", "produces": ["application/json"], "paths": {"/": {"get": {"responses": {"200": {"description": "OK", "schema": {"$ref": "#/definitions/User"}}}}}}, "definitions": {"User": {"type": "string"}}}`},
+	)
+	require.NoError(t, err)
+
+	var merged map[string]any
+	require.NoError(t, json.Unmarshal([]byte(result.Content), &merged))
+	require.Equal(t, "2.0", merged["swagger"])
+	require.Equal(t, "users.example.com", merged["host"])
+	require.Equal(t, []any{"application/json"}, merged["produces"])
+	require.Contains(t, merged["definitions"], "admin.User")
+	require.Contains(t, result.Content, `"/admin/":{"get":{"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/admin.User"}}}}}`)
+
+	require.Len(t, result.Warnings, 1)
+	require.Equal(t, MergeConflictServer, result.Warnings[0].Kind)
+	require.Equal(t, "/host", result.Warnings[0].Location)
+}
+
+func Test_MergeSpecsErrors(t *testing.T) {
+	validContent := `{"openapi": "3.0.0", "info": {"title": "Test API", "version": "1.0.0"}}`
+
+	tests := []struct {
+		name        string
+		services    []ServiceSpec
+		expectedErr error
+	}{
+		{
+			name:        "no services",
+			expectedErr: ErrInvalidMerge,
+		},
+		{
+			name:        "invalid name",
+			services:    []ServiceSpec{{Name: "order service", Content: validContent}},
+			expectedErr: ErrInvalidMerge,
+		},
+		{
+			name:        "duplicate name",
+			services:    []ServiceSpec{{Name: "orders", Content: validContent}, {Name: "orders", Content: validContent}},
+			expectedErr: ErrInvalidMerge,
+		},
+		{
+			name:        "mixed versions",
+			services:    []ServiceSpec{{Name: "orders", Content: validContent}, {Name: "legacy", Content: `{"swagger": "2.0"}`}},
+			expectedErr: ErrInvalidMerge,
+		},
+		{
+			name:        "missing version",
+			services:    []ServiceSpec{{Name: "orders", Content: `{"info": {}}`}},
+			expectedErr: ErrInvalidMerge,
+		},
+		{
+			name:        "invalid spec",
+			services:    []ServiceSpec{{Name: "orders", Content: "{broken"}},
+			expectedErr: ErrInvalidSpec,
+		},
+		{
+			name: "renamed security scheme collides with a requirement",
+			services: []ServiceSpec{
+				{Name: "orders", Content: ordersSpec},
+				{Name: "billing", Content: `{"openapi": "3.0.3", "info": {"title": "Billing", "version": "1"}, "security": [{"apiKey": [], "billing.apiKey": []}], "paths": {}, "components": {"securitySchemes": {"apiKey": {"type": "http", "scheme": "bearer"}}}}`},
+			},
+			expectedErr: ErrInvalidMerge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := MergeSpecs("All", tt.services...)
+			require.ErrorIs(t, err, tt.expectedErr)
+			require.Nil(t, result)
+		})
+	}
+}
+
+func Test_WithMerge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orders":
+			w.Write([]byte(ordersSpec))
+		case "/billing":
+			w.Write([]byte(billingSpec))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("merged spec is served", func(t *testing.T) {
+		scalar, err := NewBuilder().
+			Title("Platform").
+			Merge(
+				MergeService{Name: "orders", PathPrefix: "/orders", Options: []Option{WithURL(server.URL + "/orders")}},
+				MergeService{Name: "billing", PathPrefix: "/billing", Options: []Option{WithURL(server.URL + "/billing")}},
+			).
+			Build()
+		require.NoError(t, err)
+
+		spec := serveSpec(t, scalar)
+		require.Contains(t, spec, `"title":"Platform"`)
+		require.Contains(t, spec, `"/billing/pets"`)
+		require.Len(t, scalar.MergeWarnings(), 2)
+	})
+
+	t.Run("unreachable service", func(t *testing.T) {
+		scalar, err := NewScalar(WithMerge(
+			MergeService{Name: "orders", Options: []Option{WithURL(server.URL + "/orders")}},
+			MergeService{Name: "missing", Options: []Option{WithURL(server.URL + "/missing")}},
+		))
+		require.ErrorIs(t, err, ErrHTTPRequest)
+		require.Nil(t, scalar)
+	})
+
+	t.Run("options after the merge", func(t *testing.T) {
+		var requests atomic.Int32
+		client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requests.Add(1)
+			return http.DefaultTransport.RoundTrip(req)
+		})}
+
+		scalar, err := NewScalar(
+			WithMerge(
+				MergeService{Name: "orders", PathPrefix: "/orders", Options: []Option{WithURL(server.URL + "/orders")}},
+				MergeService{Name: "billing", PathPrefix: "/billing", Options: []Option{WithURL(server.URL + "/billing")}},
+			),
+			WithTitle("Platform"),
+			WithHTTPClient(client),
+		)
+		require.NoError(t, err)
+		require.Contains(t, serveSpec(t, scalar), `"title":"Platform"`)
+		require.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("later spec replaces the merge", func(t *testing.T) {
+		scalar, err := NewScalar(
+			WithMerge(MergeService{Name: "missing", Options: []Option{WithURL(server.URL + "/missing")}}),
+			WithSpecContent(ordersSpec),
+		)
+		require.NoError(t, err)
+		require.Empty(t, scalar.MergeWarnings())
+	})
+}
+
+func Test_RenameRef(t *testing.T) {
+	renames := map[string]map[string]string{
+		"/components/schemas": {"User": "billing.User", "a/b": "billing.a/b"},
+	}
+
+	tests := []struct {
+		name     string
+		ref      string
+		expected string
+	}{
+		{name: "renamed", ref: "#/components/schemas/User", expected: "#/components/schemas/billing.User"},
+		{name: "nested pointer", ref: "#/components/schemas/User/properties/id", expected: "#/components/schemas/billing.User/properties/id"},
+		{name: "escaped name", ref: "#/components/schemas/a~1b", expected: "#/components/schemas/billing.a~1b"},
+		{name: "not renamed", ref: "#/components/schemas/Error", expected: "#/components/schemas/Error"},
+		{name: "name prefix only", ref: "#/components/schemas/UserList", expected: "#/components/schemas/UserList"},
+		{name: "other section", ref: "#/components/responses/User", expected: "#/components/responses/User"},
+		{name: "external", ref: "common.yaml#/components/schemas/User", expected: "common.yaml#/components/schemas/User"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, renameRef(tt.ref, renames))
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package goscalar
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	// Errors
+	ErrInvalidPrefix = errors.New("invalid mount prefix")
+	ErrRouteConflict = errors.New("route conflicts with a registered route")
+)
+
+// Mount registers the documentation on a ServeMux using Go 1.22 patterns:
+// "GET {prefix}/" for the page and the specs of WithSource documents,
+// "GET {prefix}/openapi.json", "GET {prefix}/openapi.yaml", "GET {prefix}/openapi"
+// and the content-hashed bundle at "GET {prefix}/assets/api-reference.<hash>.js",
+// the only path registered under assets/. The bare prefix redirects to its slash
+// form. GET patterns also answer HEAD requests. Nothing is registered when a route
+// with the same path already exists, the error then wraps ErrRouteConflict.
+func Mount(mux *http.ServeMux, prefix string, s *Scalar) (err error) {
+	if mux == nil {
+		return errors.New("mux cannot be nil")
+	}
+	if s == nil {
+		return errors.New("scalar cannot be nil")
+	}
+	if strings.ContainsAny(prefix, "{}?# \t\r\n") {
+		return fmt.Errorf("%w: %q must be a literal path", ErrInvalidPrefix, prefix)
+	}
+	prefix = normalizePrefix(prefix)
+
+	paths := []string{
+		prefix + "/",
+		prefix + "/" + specJSONPath,
+		prefix + "/" + specYAMLPath,
+		prefix + "/" + specPath,
+		prefix + "/" + assetsPath + scriptAsset().name,
+	}
+	if prefix != "" {
+		paths = append(paths, prefix)
+	}
+
+	// A route with the same path would be shadowed by a method-specific pattern
+	// or make ServeMux panic, so conflicts are checked before registering anything
+	for _, path := range paths {
+		probe := &http.Request{Method: http.MethodGet, URL: &url.URL{Path: path}}
+		if _, pattern := mux.Handler(probe); patternPath(pattern) == path {
+			return fmt.Errorf("%w: %q is already registered", ErrRouteConflict, pattern)
+		}
+	}
+
+	// ServeMux panics on conflicts with overlapping wildcard patterns. Only the
+	// catch-all can overlap them, it is registered first so nothing is left behind.
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("%w: %v", ErrRouteConflict, recovered)
+		}
+	}()
+
+	handler := s.Handler(prefix)
+	for _, path := range paths {
+		mux.Handle(http.MethodGet+" "+path, handler)
+	}
+	return nil
+}
+
+// patternPath returns the path of a ServeMux pattern, without its method and host
+func patternPath(pattern string) string {
+	if _, rest, ok := strings.Cut(pattern, " "); ok {
+		pattern = strings.TrimSpace(rest)
+	}
+	if i := strings.Index(pattern, "/"); i >= 0 {
+		return pattern[i:]
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package goscalar
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Mount(t *testing.T) {
+	validContent := `{"openapi": "3.0.0", "info": {"title": "Mounted API", "version": "1.0.0"}}`
+
+	scalar, err := NewScalar(WithTitle("Mounted Docs"), WithSource("Pets", "pets", WithSpecContent(validContent)))
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/{resource}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("api"))
+	})
+	mux.HandleFunc("GET /docs-status", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("up"))
+	})
+	require.NoError(t, Mount(mux, "/docs/", scalar))
+
+	tests := []struct {
+		name             string
+		method           string
+		target           string
+		expectedStatus   int
+		expectedLocation string
+		expectedBody     string
+	}{
+		{name: "page", method: http.MethodGet, target: "/docs/", expectedStatus: http.StatusOK, expectedBody: "Mounted Docs"},
+		{name: "bare prefix", method: http.MethodGet, target: "/docs", expectedStatus: http.StatusMovedPermanently, expectedLocation: "/docs/"},
+		{name: "bare prefix with query", method: http.MethodGet, target: "/docs?theme=dark", expectedStatus: http.StatusMovedPermanently, expectedLocation: "/docs/?theme=dark"},
+		{name: "spec", method: http.MethodGet, target: "/docs/openapi.json", expectedStatus: http.StatusOK, expectedBody: validContent},
+		{name: "YAML spec", method: http.MethodGet, target: "/docs/openapi.yaml", expectedStatus: http.StatusOK, expectedBody: "title: Mounted API"},
+		{name: "negotiated spec", method: http.MethodGet, target: "/docs/openapi?format=yaml", expectedStatus: http.StatusOK, expectedBody: "title: Mounted API"},
+		{name: "source spec", method: http.MethodGet, target: "/docs/pets/openapi.json", expectedStatus: http.StatusOK, expectedBody: validContent},
+		{name: "asset", method: http.MethodGet, target: "/docs/" + assetsPath + scriptAsset().name, expectedStatus: http.StatusOK},
+		{name: "HEAD", method: http.MethodHead, target: "/docs/openapi.json", expectedStatus: http.StatusOK},
+		{name: "other methods", method: http.MethodPost, target: "/docs/", expectedStatus: http.StatusMethodNotAllowed},
+		{name: "unknown file", method: http.MethodGet, target: "/docs/missing.json", expectedStatus: http.StatusNotFound},
+		{name: "other routes", method: http.MethodGet, target: "/api/pets", expectedStatus: http.StatusOK, expectedBody: "api"},
+		{name: "routes sharing the prefix", method: http.MethodGet, target: "/docs-status", expectedStatus: http.StatusOK, expectedBody: "up"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			require.Equal(t, tt.expectedLocation, rec.Header().Get("Location"))
+			require.Contains(t, rec.Body.String(), tt.expectedBody)
+		})
+	}
+}
+
+func Test_MountErrors(t *testing.T) {
+	scalar, err := NewScalar(WithSpecContent(`{"openapi": "3.0.0", "info": {"title": "Mounted API"}}`))
+	require.NoError(t, err)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name        string
+		existing    []string
+		prefix      string
+		expectedErr error
+	}{
+		{name: "same catch-all", existing: []string{"/docs/"}, prefix: "/docs", expectedErr: ErrRouteConflict},
+		{name: "same spec route", existing: []string{"GET /docs/openapi.json"}, prefix: "/docs", expectedErr: ErrRouteConflict},
+		{name: "other method on the same path", existing: []string{"POST /docs"}, prefix: "/docs"},
+		{name: "root catch-all", existing: []string{"/"}, prefix: "", expectedErr: ErrRouteConflict},
+		{name: "overlapping wildcard", existing: []string{"GET /{section}/openapi.json"}, prefix: "/docs", expectedErr: ErrRouteConflict},
+		{name: "root catch-all with a prefix", existing: []string{"/"}, prefix: "/docs"},
+		{name: "wildcard prefix", prefix: "/{tenant}/docs", expectedErr: ErrInvalidPrefix},
+		{name: "query in the prefix", prefix: "/docs?x=1", expectedErr: ErrInvalidPrefix},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			for _, pattern := range tt.existing {
+				mux.Handle(pattern, handler)
+			}
+
+			err := Mount(mux, tt.prefix, scalar)
+			if tt.expectedErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tt.expectedErr)
+
+			// Nothing was registered
+			_, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, "/docs/"+specYAMLPath, nil))
+			require.NotEqual(t, "GET /docs/"+specYAMLPath, pattern)
+		})
+	}
+
+	t.Run("nil arguments", func(t *testing.T) {
+		require.Error(t, Mount(nil, "/docs", scalar))
+		require.Error(t, Mount(http.NewServeMux(), "/docs", nil))
+	})
+}
@@ -0,0 +1,54 @@
+// Package muxscalar serves goscalar documentation from a gorilla/mux router
+package muxscalar
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/JhonatanRSantos/goscalar"
+)
+
+// Mount serves the documentation page, the raw spec and the Scalar bundle under the
+// prefix, e.g. Mount(r, "/docs", scalar) serves "/docs/", "/docs/openapi.json" and
+// "/docs/assets/", redirecting "/docs" to "/docs/". The middlewares of the router
+// apply, and middlewares are added to the documentation routes only. Subrouters
+// and prefixes with variables are supported.
+func Mount(router *mux.Router, prefix string, s *goscalar.Scalar, middlewares ...mux.MiddlewareFunc) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" {
+		prefix = ""
+	}
+
+	// A subrouter without matchers scopes the middlewares, requests it does not
+	// match fall through to the routes registered after it
+	docs := router.NewRoute().Subrouter()
+	docs.Use(middlewares...)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Handler(mountPoint(r)).ServeHTTP(w, r)
+	})
+	if prefix != "" {
+		docs.Path(prefix).Methods(http.MethodGet, http.MethodHead).Handler(handler)
+	}
+	docs.PathPrefix(prefix+"/").Methods(http.MethodGet, http.MethodHead).Handler(handler)
+}
+
+// mountPoint returns the path the matched route was mounted at, with its variables expanded
+func mountPoint(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+
+	var pairs []string
+	for key, value := range mux.Vars(r) {
+		pairs = append(pairs, key, value)
+	}
+	mountURL, err := route.URLPath(pairs...)
+	if err != nil {
+		return ""
+	}
+	return mountURL.Path
+}
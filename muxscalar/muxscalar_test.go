@@ -0,0 +1,104 @@
+package muxscalar
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/JhonatanRSantos/goscalar"
+)
+
+const validContent = `{"openapi": "3.0.0", "info": {"title": "Mux API", "version": "1.0.0"}}`
+
+// header returns a middleware setting a response header
+func header(key, value string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(key, value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func Test_Mount(t *testing.T) {
+	scalar, err := goscalar.NewScalar(goscalar.WithTitle("Mux Docs"), goscalar.WithSpecContent(validContent))
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	Mount(router, "/docs", scalar)
+
+	api := router.PathPrefix("/api/{version}").Subrouter()
+	api.Use(header("X-Group", "api"))
+	Mount(api, "/docs", scalar, header("X-Handler", "docs"))
+
+	// Routes sharing the prefix registered after the documentation still match
+	router.HandleFunc("/docs-status", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("up"))
+	})
+	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	assetPath := scriptPath(t, router)
+
+	tests := []struct {
+		name             string
+		method           string
+		target           string
+		expectedStatus   int
+		expectedLocation string
+		expectedBody     string
+		expectedHeaders  map[string]string
+	}{
+		{name: "page", method: http.MethodGet, target: "/docs/", expectedStatus: http.StatusOK, expectedBody: "Mux Docs"},
+		{name: "bare prefix", method: http.MethodGet, target: "/docs", expectedStatus: http.StatusMovedPermanently, expectedLocation: "/docs/"},
+		{name: "spec", method: http.MethodGet, target: "/docs/openapi.json", expectedStatus: http.StatusOK, expectedBody: validContent},
+		{name: "YAML spec", method: http.MethodGet, target: "/docs/openapi.yaml", expectedStatus: http.StatusOK, expectedBody: "title: Mux API"},
+		{name: "asset", method: http.MethodGet, target: "/docs/" + assetPath, expectedStatus: http.StatusOK},
+		{name: "HEAD", method: http.MethodHead, target: "/docs/openapi.json", expectedStatus: http.StatusOK},
+		{name: "unknown file", method: http.MethodGet, target: "/docs/missing.json", expectedStatus: http.StatusNotFound},
+		{
+			name:            "nested group with middleware",
+			method:          http.MethodGet,
+			target:          "/api/v1/docs/openapi.json",
+			expectedStatus:  http.StatusOK,
+			expectedBody:    validContent,
+			expectedHeaders: map[string]string{"X-Group": "api", "X-Handler": "docs"},
+		},
+		{name: "nested bare prefix", method: http.MethodGet, target: "/api/v2/docs", expectedStatus: http.StatusMovedPermanently, expectedLocation: "/api/v2/docs/"},
+		{name: "other routes", method: http.MethodGet, target: "/health", expectedStatus: http.StatusOK, expectedBody: "ok"},
+		{name: "routes sharing the prefix", method: http.MethodGet, target: "/docs-status", expectedStatus: http.StatusOK, expectedBody: "up"},
+		{name: "other methods", method: http.MethodPost, target: "/docs/", expectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			require.Equal(t, tt.expectedLocation, rec.Header().Get("Location"))
+			require.Contains(t, rec.Body.String(), tt.expectedBody)
+			for key, value := range tt.expectedHeaders {
+				require.Equal(t, value, rec.Header().Get(key))
+			}
+		})
+	}
+}
+
+// scriptPath returns the path of the Scalar bundle referenced by the page, relative to it
+func scriptPath(t *testing.T, router http.Handler) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/", nil))
+
+	_, rest, ok := strings.Cut(rec.Body.String(), `<script src="`)
+	require.True(t, ok)
+	path, _, _ := strings.Cut(rest, `"`)
+	return path
+}
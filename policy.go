@@ -0,0 +1,309 @@
+package goscalar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultMaxRedirects = 5
+	defaultMaxBodySize  = 10 << 20 // 10 MiB
+)
+
+var (
+	ErrInvalidFetchPolicy = errors.New("invalid fetch policy")
+	ErrHostNotAllowed     = errors.New("host is not allowed by the fetch policy")
+	ErrPrivateAddress     = errors.New("private network addresses are blocked by the fetch policy")
+	ErrTooManyRedirects   = errors.New("too many redirects")
+	ErrResponseTooLarge   = errors.New("response body exceeds the size limit")
+)
+
+var (
+	// reservedPrefixes are non-public ranges netip has no predicate for
+	reservedPrefixes = []netip.Prefix{
+		netip.MustParsePrefix("100.64.0.0/10"),  // Shared address space of carrier-grade NAT (RFC 6598)
+		netip.MustParsePrefix("198.18.0.0/15"),  // Benchmarking (RFC 2544)
+		netip.MustParsePrefix("240.0.0.0/4"),    // Reserved, including the limited broadcast address
+		netip.MustParsePrefix("64:ff9b:1::/48"), // Local-use NAT64 (RFC 8215)
+	}
+
+	// Prefixes embedding an IPv4 address, which reaches the network of that address
+	nat64Prefix     = netip.MustParsePrefix("64:ff9b::/96") // Well-known NAT64 prefix (RFC 6052)
+	sixToFourPrefix = netip.MustParsePrefix("2002::/16")    // 6to4 (RFC 3056)
+)
+
+// FetchPolicy restricts the URLs WithURL may fetch, e.g. when tenants register their
+// own spec URL. Hosts and addresses are checked when connecting, for the first request
+// and every redirect, so DNS rebinding cannot get around the policy.
+type FetchPolicy struct {
+	// AllowHosts, when set, lists the only hosts that may be fetched. Entries are host
+	// names, "*.example.com" for subdomains, IP addresses or CIDR prefixes.
+	AllowHosts []string
+	// DenyHosts lists hosts that may never be fetched, with the same entries as AllowHosts
+	DenyHosts []string
+	// AllowPrivateNetworks allows loopback, private, link-local (including cloud metadata
+	// services), CGNAT, benchmarking, multicast, reserved and unspecified addresses, as well
+	// as NAT64 and 6to4 addresses embedding them. Otherwise they are only reachable when
+	// an AllowHosts prefix or address lists them.
+	AllowPrivateNetworks bool
+	// MaxRedirects caps the redirects followed, zero means 5 and a negative value none
+	MaxRedirects int
+	// MaxBodySize caps the decoded response size in bytes, zero means 10 MiB
+	MaxBodySize int64
+}
+
+// PolicyError reports a request the fetch policy refused
+type PolicyError struct {
+	Host    string // Host of the refused request
+	Address string // Address the host resolved to, if known
+	Err     error  // One of the fetch policy errors
+}
+
+func (e *PolicyError) Error() string {
+	if e.Address != "" {
+		return fmt.Sprintf("%s (%s): %v", e.Host, e.Address, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Host, e.Err)
+}
+
+func (e *PolicyError) Unwrap() error {
+	return e.Err
+}
+
+// WithFetchPolicy restricts the hosts, addresses, redirects and response sizes of WithURL.
+// The policy also applies to WithSource and WithMerge documents.
+func WithFetchPolicy(policy FetchPolicy) Option {
+	return func(s *Scalar) error {
+		if _, err := policy.compile(); err != nil {
+			return err
+		}
+		s.fetchPolicy = &policy
+		return nil
+	}
+}
+
+// FetchPolicy restricts what URL sources may fetch
+func (b *Builder) FetchPolicy(policy FetchPolicy) *Builder {
+	b.options = append(b.options, WithFetchPolicy(policy))
+	return b
+}
+
+// hostRules are parsed AllowHosts or DenyHosts entries
+type hostRules struct {
+	names    []string // Lower case host names, "*." prefixed for subdomains
+	prefixes []netip.Prefix
+}
+
+// parseHostRules parses host list entries
+func parseHostRules(entries []string) (hostRules, error) {
+	var rules hostRules
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(entry), "."))
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			rules.prefixes = append(rules.prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			rules.prefixes = append(rules.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		name := strings.TrimPrefix(entry, "*.")
+		if name == "" || strings.ContainsAny(name, "*/:") {
+			return hostRules{}, fmt.Errorf("%w: invalid host %q", ErrInvalidFetchPolicy, entry)
+		}
+		rules.names = append(rules.names, entry)
+	}
+	return rules, nil
+}
+
+func (r hostRules) empty() bool {
+	return len(r.names) == 0 && len(r.prefixes) == 0
+}
+
+// matchName reports whether a host name is listed
+func (r hostRules) matchName(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, name := range r.names {
+		if suffix, ok := strings.CutPrefix(name, "*"); ok {
+			if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+				return true
+			}
+		} else if host == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAddr reports whether an address is listed
+func (r hostRules) matchAddr(addr netip.Addr) bool {
+	for _, prefix := range r.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchPolicy is a parsed FetchPolicy
+type fetchPolicy struct {
+	allow, deny  hostRules
+	allowPrivate bool
+	maxRedirects int
+	maxBodySize  int64
+}
+
+// compile validates the policy and applies its defaults
+func (p FetchPolicy) compile() (*fetchPolicy, error) {
+	if p.MaxBodySize < 0 {
+		return nil, fmt.Errorf("%w: MaxBodySize cannot be negative", ErrInvalidFetchPolicy)
+	}
+
+	allow, err := parseHostRules(p.AllowHosts)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := parseHostRules(p.DenyHosts)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := &fetchPolicy{
+		allow:        allow,
+		deny:         deny,
+		allowPrivate: p.AllowPrivateNetworks,
+		maxRedirects: p.MaxRedirects,
+		maxBodySize:  p.MaxBodySize,
+	}
+	if compiled.maxRedirects == 0 {
+		compiled.maxRedirects = defaultMaxRedirects
+	}
+	if compiled.maxBodySize == 0 {
+		compiled.maxBodySize = defaultMaxBodySize
+	}
+	return compiled, nil
+}
+
+// checkHost checks a host name before it is resolved
+func (p *fetchPolicy) checkHost(host string) error {
+	if p.deny.matchName(host) {
+		return &PolicyError{Host: host, Err: ErrHostNotAllowed}
+	}
+	// Without prefixes, unlisted names can be refused before any DNS lookup
+	if len(p.allow.names) > 0 && len(p.allow.prefixes) == 0 && !p.allow.matchName(host) {
+		return &PolicyError{Host: host, Err: ErrHostNotAllowed}
+	}
+	return nil
+}
+
+// checkAddr checks the address a host resolved to
+func (p *fetchPolicy) checkAddr(host string, addr netip.Addr) error {
+	addr = addr.Unmap()
+	switch {
+	case p.deny.matchAddr(addr):
+		return &PolicyError{Host: host, Address: addr.String(), Err: ErrHostNotAllowed}
+	case p.allow.matchAddr(addr):
+		// Listed addresses may be private
+		return nil
+	case !p.allow.empty() && !p.allow.matchName(host):
+		return &PolicyError{Host: host, Address: addr.String(), Err: ErrHostNotAllowed}
+	case !p.allowPrivate && isPrivateAddr(addr):
+		return &PolicyError{Host: host, Address: addr.String(), Err: ErrPrivateAddress}
+	}
+	return nil
+}
+
+// client returns a copy of client enforcing the policy. The copy dials directly,
+// since a proxy would connect to the checked hosts on its behalf.
+func (p *fetchPolicy) client(client *http.Client) (*http.Client, error) {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	transport, ok := base.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("%w: the HTTP client transport must be an *http.Transport", ErrInvalidFetchPolicy)
+	}
+
+	transport = transport.Clone()
+	transport.Proxy = nil
+	transport.DialTLSContext = nil
+	transport.DialContext = p.dialContext
+
+	policyClient := *client
+	policyClient.Transport = transport
+	policyClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > p.maxRedirects {
+			return &PolicyError{Host: req.URL.Hostname(), Err: ErrTooManyRedirects}
+		}
+		if client.CheckRedirect != nil {
+			return client.CheckRedirect(req, via)
+		}
+		return nil
+	}
+	return &policyClient, nil
+}
+
+// dialContext connects to the addresses the policy allows
+func (p *fetchPolicy) dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkHost(host); err != nil {
+		return nil, err
+	}
+
+	// The resolved address is checked right before connecting to it
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			return p.checkAddr(host, addrPort.Addr())
+		},
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// isPrivateAddr reports whether an address belongs to a non-public network. IPv4
+// addresses embedded in NAT64 and 6to4 addresses are checked as well.
+func isPrivateAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if embedded, ok := embeddedIPv4(addr); ok && isPrivateAddr(embedded) {
+		return true
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() ||
+		(addr.Is4() && addr.As4()[0] == 0)
+}
+
+// embeddedIPv4 returns the IPv4 address a NAT64 or 6to4 address embeds
+func embeddedIPv4(addr netip.Addr) (netip.Addr, bool) {
+	bytes := addr.As16()
+	switch {
+	case nat64Prefix.Contains(addr):
+		return netip.AddrFrom4([4]byte(bytes[12:16])), true
+	case sixToFourPrefix.Contains(addr):
+		return netip.AddrFrom4([4]byte(bytes[2:6])), true
+	}
+	return netip.Addr{}, false
+}
@@ -0,0 +1,238 @@
+package goscalar
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const policySpec = `{"openapi": "3.0.0", "info": {"title": "Tenant API"}}`
+
+// roundTripperFunc is an HTTP transport that is not an *http.Transport
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// gzipped compresses content
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func Test_WithFetchPolicy(t *testing.T) {
+	large := `{"openapi": "3.0.0", "info": {"title": "` + strings.Repeat("x", 4096) + `"}}`
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/spec":
+			w.Write([]byte(policySpec))
+		case "/large":
+			w.Write([]byte(large))
+		case "/large-chunked":
+			w.(http.Flusher).Flush()
+			w.Write([]byte(large))
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipped(t, policySpec))
+		case "/gzip-bomb":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipped(t, large))
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/to-localhost":
+			target, _ := url.Parse(server.URL)
+			http.Redirect(w, r, "http://localhost:"+target.Port()+"/spec", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	uncompressed := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+
+	tests := []struct {
+		name            string
+		path            string
+		client          *http.Client
+		policy          FetchPolicy
+		expectedErr     error
+		expectedAddress string
+	}{
+		{name: "loopback is blocked", path: "/spec", expectedErr: ErrPrivateAddress, expectedAddress: "127.0.0.1"},
+		{name: "allowed prefix", path: "/spec", policy: FetchPolicy{AllowHosts: []string{"127.0.0.0/8"}}},
+		{name: "allowed address", path: "/spec", policy: FetchPolicy{AllowHosts: []string{"127.0.0.1"}}},
+		{name: "private networks allowed", path: "/spec", policy: FetchPolicy{AllowPrivateNetworks: true}},
+		{name: "unlisted host", path: "/spec", policy: FetchPolicy{AllowHosts: []string{"docs.example.com"}}, expectedErr: ErrHostNotAllowed},
+		{name: "denied prefix", path: "/spec", policy: FetchPolicy{AllowPrivateNetworks: true, DenyHosts: []string{"127.0.0.0/8"}}, expectedErr: ErrHostNotAllowed, expectedAddress: "127.0.0.1"},
+		{name: "redirect to a denied host", path: "/to-localhost", policy: FetchPolicy{AllowPrivateNetworks: true, DenyHosts: []string{"localhost"}}, expectedErr: ErrHostNotAllowed},
+		{name: "redirect loop", path: "/loop", policy: FetchPolicy{AllowPrivateNetworks: true, MaxRedirects: 2}, expectedErr: ErrTooManyRedirects},
+		{name: "redirects disabled", path: "/to-localhost", policy: FetchPolicy{AllowPrivateNetworks: true, MaxRedirects: -1}, expectedErr: ErrTooManyRedirects},
+		{name: "response too large", path: "/large", policy: FetchPolicy{AllowPrivateNetworks: true, MaxBodySize: 1024}, expectedErr: ErrResponseTooLarge},
+		{name: "chunked response too large", path: "/large-chunked", policy: FetchPolicy{AllowPrivateNetworks: true, MaxBodySize: 1024}, expectedErr: ErrResponseTooLarge},
+		{name: "gzip response", path: "/gzip", client: uncompressed, policy: FetchPolicy{AllowPrivateNetworks: true}},
+		{name: "decoded size is limited", path: "/gzip-bomb", client: uncompressed, policy: FetchPolicy{AllowPrivateNetworks: true, MaxBodySize: 1024}, expectedErr: ErrResponseTooLarge},
+		{
+			name:        "custom transport",
+			path:        "/spec",
+			client:      &http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)},
+			policy:      FetchPolicy{AllowPrivateNetworks: true},
+			expectedErr: ErrInvalidFetchPolicy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := []Option{WithFetchPolicy(tt.policy)}
+			if tt.client != nil {
+				options = append(options, WithHTTPClient(tt.client))
+			}
+
+			scalar, err := FromURL(server.URL+tt.path, options...)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				require.Nil(t, scalar)
+
+				var policyErr *PolicyError
+				if errors.As(err, &policyErr) {
+					require.Equal(t, tt.expectedAddress, policyErr.Address)
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			defer scalar.Close()
+			require.Contains(t, serveSpec(t, scalar), "Tenant API")
+		})
+	}
+
+	t.Run("allowed names still resolve to checked addresses", func(t *testing.T) {
+		target, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		// As with DNS rebinding, an allowed name pointing at loopback is refused
+		_, err = FromURL("http://localhost:"+target.Port()+"/spec", WithFetchPolicy(FetchPolicy{AllowHosts: []string{"localhost"}}))
+		require.ErrorIs(t, err, ErrPrivateAddress)
+	})
+
+	t.Run("sources use the policy", func(t *testing.T) {
+		_, err := NewScalar(
+			WithFetchPolicy(FetchPolicy{}),
+			WithSource("Tenant", "tenant", WithURL(server.URL+"/spec")),
+		)
+		require.ErrorIs(t, err, ErrPrivateAddress)
+	})
+
+	t.Run("policy after a source", func(t *testing.T) {
+		_, err := NewScalar(
+			WithSource("Tenant", "tenant", WithURL(server.URL+"/spec")),
+			WithFetchPolicy(FetchPolicy{}),
+		)
+		require.ErrorIs(t, err, ErrPrivateAddress)
+	})
+
+	t.Run("builder", func(t *testing.T) {
+		scalar, err := NewBuilder().URL(server.URL + "/spec").FetchPolicy(FetchPolicy{AllowPrivateNetworks: true}).Build()
+		require.NoError(t, err)
+		defer scalar.Close()
+	})
+}
+
+func Test_FetchPolicyCompile(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      FetchPolicy
+		expectedErr error
+	}{
+		{name: "zero policy", policy: FetchPolicy{}},
+		{name: "host entries", policy: FetchPolicy{AllowHosts: []string{"docs.example.com", "*.example.org", "10.0.0.0/8", "::1"}}},
+		{name: "wildcard everything", policy: FetchPolicy{AllowHosts: []string{"*"}}, expectedErr: ErrInvalidFetchPolicy},
+		{name: "host with a port", policy: FetchPolicy{DenyHosts: []string{"example.com:8080"}}, expectedErr: ErrInvalidFetchPolicy},
+		{name: "empty entry", policy: FetchPolicy{DenyHosts: []string{" "}}, expectedErr: ErrInvalidFetchPolicy},
+		{name: "negative body size", policy: FetchPolicy{MaxBodySize: -1}, expectedErr: ErrInvalidFetchPolicy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := tt.policy.compile()
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, int64(defaultMaxBodySize), policy.maxBodySize)
+			require.Equal(t, defaultMaxRedirects, policy.maxRedirects)
+		})
+	}
+}
+
+func Test_FetchPolicyCheckAddr(t *testing.T) {
+	policy, err := FetchPolicy{
+		AllowHosts: []string{"*.example.com", "10.1.0.0/16"},
+		DenyHosts:  []string{"blocked.example.com", "203.0.113.7"},
+	}.compile()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		host        string
+		addr        string
+		expectedErr error
+	}{
+		{name: "allowed subdomain", host: "docs.example.com", addr: "93.184.216.34"},
+		{name: "apex is not a subdomain", host: "example.com", addr: "93.184.216.34", expectedErr: ErrHostNotAllowed},
+		{name: "unlisted host", host: "docs.example.net", addr: "93.184.216.34", expectedErr: ErrHostNotAllowed},
+		{name: "listed private prefix", host: "internal", addr: "10.1.2.3"},
+		{name: "allowed name on a private address", host: "docs.example.com", addr: "10.2.0.1", expectedErr: ErrPrivateAddress},
+		{name: "cloud metadata", host: "docs.example.com", addr: "169.254.169.254", expectedErr: ErrPrivateAddress},
+		{name: "IPv4-mapped loopback", host: "docs.example.com", addr: "::ffff:127.0.0.1", expectedErr: ErrPrivateAddress},
+		{name: "IPv6 unique local", host: "docs.example.com", addr: "fd00:ec2::254", expectedErr: ErrPrivateAddress},
+		{name: "carrier-grade NAT", host: "docs.example.com", addr: "100.64.0.1", expectedErr: ErrPrivateAddress},
+		{name: "unspecified", host: "docs.example.com", addr: "0.0.0.0", expectedErr: ErrPrivateAddress},
+		{name: "benchmarking", host: "docs.example.com", addr: "198.19.255.1", expectedErr: ErrPrivateAddress},
+		{name: "limited broadcast", host: "docs.example.com", addr: "255.255.255.255", expectedErr: ErrPrivateAddress},
+		{name: "reserved", host: "docs.example.com", addr: "240.0.0.1", expectedErr: ErrPrivateAddress},
+		{name: "IPv4 multicast", host: "docs.example.com", addr: "239.255.255.250", expectedErr: ErrPrivateAddress},
+		{name: "IPv6 multicast", host: "docs.example.com", addr: "ff0e::1", expectedErr: ErrPrivateAddress},
+		{name: "NAT64 private", host: "docs.example.com", addr: "64:ff9b::10.0.0.1", expectedErr: ErrPrivateAddress},
+		{name: "NAT64 metadata", host: "docs.example.com", addr: "64:ff9b::a9fe:a9fe", expectedErr: ErrPrivateAddress},
+		{name: "NAT64 public", host: "docs.example.com", addr: "64:ff9b::93.184.216.34"},
+		{name: "local-use NAT64", host: "docs.example.com", addr: "64:ff9b:1::5db8:d822", expectedErr: ErrPrivateAddress},
+		{name: "6to4 loopback", host: "docs.example.com", addr: "2002:7f00:1::1", expectedErr: ErrPrivateAddress},
+		{name: "6to4 public", host: "docs.example.com", addr: "2002:5db8:d822::1"},
+		{name: "public IPv6", host: "docs.example.com", addr: "2606:2800:220:1:248:1893:25c8:1946"},
+		{name: "denied address", host: "docs.example.com", addr: "203.0.113.7", expectedErr: ErrHostNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.checkAddr(tt.host, netip.MustParseAddr(tt.addr))
+			if tt.expectedErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+
+	t.Run("denied names are refused before resolving", func(t *testing.T) {
+		require.ErrorIs(t, policy.checkHost("BLOCKED.example.com."), ErrHostNotAllowed)
+		require.NoError(t, policy.checkHost("docs.example.com"))
+	})
+}
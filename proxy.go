@@ -0,0 +1,186 @@
+package goscalar
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+)
+
+var (
+	// Errors
+	ErrInvalidExternalURL = errors.New("invalid external URL")
+	ErrInvalidProxy       = errors.New("invalid trusted proxy")
+)
+
+// WithExternalURL sets the URL clients reach the mount point at, e.g.
+// "https://api.example.com/payments/docs" when an ingress serves the "/docs" mount
+// under "/payments". Redirects and the links of the page are built from it, and
+// forwarded headers are ignored.
+func WithExternalURL(rawURL string) Option {
+	return func(s *Scalar) error {
+		external, err := url.Parse(strings.TrimSpace(rawURL))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidExternalURL, err)
+		}
+		if (external.Scheme != httpScheme && external.Scheme != httpsScheme) || external.Host == "" {
+			return fmt.Errorf("%w: %q must be an absolute http or https URL", ErrInvalidExternalURL, rawURL)
+		}
+		if external.User != nil || external.RawQuery != "" || external.Fragment != "" {
+			return fmt.Errorf("%w: %q cannot have credentials, a query or a fragment", ErrInvalidExternalURL, rawURL)
+		}
+
+		s.config.ExternalURL = (&url.URL{
+			Scheme: external.Scheme,
+			Host:   external.Host,
+			Path:   normalizePrefix(external.Path),
+		}).String()
+		return nil
+	}
+}
+
+// ExternalURL sets the URL clients reach the mount point at
+func (b *Builder) ExternalURL(rawURL string) *Builder {
+	b.options = append(b.options, WithExternalURL(rawURL))
+	return b
+}
+
+// WithTrustedProxies honors the Forwarded, X-Forwarded-Proto, X-Forwarded-Host and
+// X-Forwarded-Prefix headers of requests coming from the given proxies, as IP
+// addresses or CIDR prefixes. The values added by the nearest proxy are used, so
+// it must overwrite or append to headers sent by clients. Requests from other
+// addresses are served as if there were no proxy.
+func WithTrustedProxies(proxies ...string) Option {
+	return func(s *Scalar) error {
+		prefixes := make([]netip.Prefix, 0, len(proxies))
+		for _, proxy := range proxies {
+			proxy = strings.TrimSpace(proxy)
+			if prefix, err := netip.ParsePrefix(proxy); err == nil {
+				prefixes = append(prefixes, prefix.Masked())
+				continue
+			}
+			addr, err := netip.ParseAddr(proxy)
+			if err != nil {
+				return fmt.Errorf("%w: %q is not an IP address or CIDR prefix", ErrInvalidProxy, proxy)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		}
+		s.config.TrustedProxies = prefixes
+		return nil
+	}
+}
+
+// TrustedProxies sets the proxies whose forwarded headers are honored
+func (b *Builder) TrustedProxies(proxies ...string) *Builder {
+	b.options = append(b.options, WithTrustedProxies(proxies...))
+	return b
+}
+
+// externalBase returns the URL clients reach the mount point at, mountPath being its
+// path on this server. It reports false when neither an external URL nor a trusted
+// proxy tells it, links are then relative to the request.
+func (c *Config) externalBase(r *http.Request, mountPath string) (*url.URL, bool) {
+	if c.ExternalURL != "" {
+		external, err := url.Parse(c.ExternalURL)
+		if err != nil {
+			return nil, false
+		}
+		return external, true
+	}
+	if !c.trustedProxy(r) {
+		return nil, false
+	}
+
+	base := &url.URL{Scheme: httpScheme, Host: r.Host, Path: mountPath}
+	if r.TLS != nil {
+		base.Scheme = httpsScheme
+	}
+
+	forwarded := lastForwarded(r.Header)
+	proto, ok := forwarded["proto"]
+	if !ok {
+		proto = lastHeaderValue(r.Header, "X-Forwarded-Proto")
+	}
+	if proto = strings.ToLower(proto); proto == httpScheme || proto == httpsScheme {
+		base.Scheme = proto
+	}
+
+	host, ok := forwarded["host"]
+	if !ok {
+		host = lastHeaderValue(r.Header, "X-Forwarded-Host")
+	}
+	if validForwardedHost(host) {
+		base.Host = host
+	}
+
+	if prefix := lastHeaderValue(r.Header, "X-Forwarded-Prefix"); strings.HasPrefix(prefix, "/") && !strings.ContainsAny(prefix, "?#\\") {
+		base.Path = normalizePrefix(path.Clean(prefix)) + mountPath
+	}
+	return base, true
+}
+
+// trustedProxy reports whether the request comes from a trusted proxy
+func (c *Config) trustedProxy(r *http.Request) bool {
+	if len(c.TrustedProxies) == 0 {
+		return false
+	}
+	addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	addr := addrPort.Addr().Unmap()
+	for _, prefix := range c.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastForwarded returns the parameters of the last Forwarded element (RFC 7239),
+// the one added by the nearest proxy, with lower case names
+func lastForwarded(header http.Header) map[string]string {
+	element := lastHeaderValue(header, "Forwarded")
+	if element == "" {
+		return nil
+	}
+
+	params := map[string]string{}
+	for _, pair := range strings.Split(element, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = strings.ReplaceAll(value[1:len(value)-1], `\"`, `"`)
+		}
+		params[strings.ToLower(name)] = value
+	}
+	return params
+}
+
+// lastHeaderValue returns the last element of a comma separated header, which may
+// be split across several lines
+func lastHeaderValue(header http.Header, name string) string {
+	values := header.Values(name)
+	if len(values) == 0 {
+		return ""
+	}
+	last := values[len(values)-1]
+	if i := strings.LastIndex(last, ","); i >= 0 {
+		last = last[i+1:]
+	}
+	return strings.TrimSpace(last)
+}
+
+// validForwardedHost reports whether a forwarded host is a plain host with an optional port
+func validForwardedHost(host string) bool {
+	if host == "" || strings.ContainsAny(host, "/\\?#@ \t") {
+		return false
+	}
+	parsed, err := url.Parse("//" + host)
+	return err == nil && parsed.Host == host
+}
@@ -0,0 +1,214 @@
+package goscalar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"slices"
+)
+
+const (
+	// Themes
+	ThemeAlternate  = "alternate"
+	ThemeDefault    = "default"
+	ThemeMoon       = "moon"
+	ThemePurple     = "purple"
+	ThemeSolarized  = "solarized"
+	ThemeBluePlanet = "bluePlanet"
+	ThemeDeepSpace  = "deepSpace"
+	ThemeSaturn     = "saturn"
+	ThemeKepler     = "kepler"
+	ThemeElysiaJS   = "elysiajs"
+	ThemeFastify    = "fastify"
+	ThemeMars       = "mars"
+	ThemeLaserwave  = "laserwave"
+	ThemeNone       = "none"
+
+	// Layouts
+	LayoutModern  = "modern"
+	LayoutClassic = "classic"
+)
+
+var (
+	// Allowed values of the enumerated options, as validated by Scalar
+	referenceThemes = []string{
+		ThemeAlternate, ThemeDefault, ThemeMoon, ThemePurple, ThemeSolarized, ThemeBluePlanet, ThemeDeepSpace,
+		ThemeSaturn, ThemeKepler, ThemeElysiaJS, ThemeFastify, ThemeMars, ThemeLaserwave, ThemeNone,
+	}
+	referenceLayouts          = []string{LayoutModern, LayoutClassic}
+	referenceDownloadTypes    = []string{"yaml", "json", "both", "none"}
+	referenceDarkModeStates   = []string{"dark", "light"}
+	referenceTagsSorters      = []string{"alpha"}
+	referenceOperationSorters = []string{"alpha", "method"}
+
+	// Errors
+	ErrInvalidReferenceConfig = errors.New("invalid reference config")
+)
+
+// ReferenceConfig holds the options passed to Scalar.createApiReference.
+// Zero values are omitted, so Scalar applies its own defaults for them.
+// Callback options (onLoaded, generateHeadingSlug, plugins, ...) cannot be set from Go.
+//
+// Docs: https://guides.scalar.com/scalar/scalar-api-references/configuration
+type ReferenceConfig struct {
+	// Theme is one of the Theme* constants
+	Theme string `json:"theme,omitempty"`
+	// Layout is LayoutModern or LayoutClassic
+	Layout string `json:"layout,omitempty"`
+	// ProxyURL routes "Try it" requests through a proxy, e.g. https://proxy.scalar.com
+	ProxyURL string `json:"proxyUrl,omitempty"`
+	// IsEditable shows the spec editor
+	IsEditable bool `json:"isEditable,omitempty"`
+	// ShowSidebar toggles the sidebar, Scalar shows it by default
+	ShowSidebar *bool `json:"showSidebar,omitempty"`
+	// HideModels hides the models section
+	HideModels bool `json:"hideModels,omitempty"`
+	// DocumentDownloadType is one of "yaml", "json", "both" or "none"
+	DocumentDownloadType string `json:"documentDownloadType,omitempty"`
+	// HideDownloadButton hides the spec download button.
+	// Deprecated: Scalar prefers DocumentDownloadType "none".
+	HideDownloadButton bool `json:"hideDownloadButton,omitempty"`
+	// HideTestRequestButton hides the "Test Request" button
+	HideTestRequestButton bool `json:"hideTestRequestButton,omitempty"`
+	// HideSearch hides the sidebar search bar
+	HideSearch bool `json:"hideSearch,omitempty"`
+	// HideClientButton hides the button that opens the API client
+	HideClientButton bool `json:"hideClientButton,omitempty"`
+	// DarkMode enables or disables the dark mode on first load, nil keeps the default: enabled
+	DarkMode *bool `json:"darkMode,omitempty"`
+	// ForceDarkModeState forces "dark" or "light" regardless of the user preference
+	ForceDarkModeState string `json:"forceDarkModeState,omitempty"`
+	// HideDarkModeToggle hides the dark mode toggle
+	HideDarkModeToggle bool `json:"hideDarkModeToggle,omitempty"`
+	// SearchHotKey is the letter used with CTRL/CMD to open the search, "a" to "z"
+	SearchHotKey string `json:"searchHotKey,omitempty"`
+	// MetaData sets the page meta tags, e.g. {"title": "...", "description": "..."}
+	MetaData map[string]string `json:"metaData,omitempty"`
+	// Favicon is the path or URL of the page favicon
+	Favicon string `json:"favicon,omitempty"`
+	// HiddenClients is true, a list of client keys, or a map of targets to bools or client keys
+	HiddenClients any `json:"hiddenClients,omitempty"`
+	// DefaultHTTPClient selects the client shown by default in the code samples
+	DefaultHTTPClient *ReferenceHTTPClient `json:"defaultHttpClient,omitempty"`
+	// CustomCSS is injected into the page
+	CustomCSS string `json:"customCss,omitempty"`
+	// PathRouting enables path based routing instead of hash based routing
+	PathRouting *ReferencePathRouting `json:"pathRouting,omitempty"`
+	// BaseServerURL prefixes relative server URLs
+	BaseServerURL string `json:"baseServerURL,omitempty"`
+	// Servers overrides the servers of the spec
+	Servers []ReferenceServer `json:"servers,omitempty"`
+	// Authentication prefills the authentication, see the Scalar docs for its shape
+	Authentication map[string]any `json:"authentication,omitempty"`
+	// PersistAuth stores the authentication in the browser local storage
+	PersistAuth bool `json:"persistAuth,omitempty"`
+	// WithDefaultFonts loads the default fonts from the Scalar CDN, Scalar enables it by default
+	WithDefaultFonts *bool `json:"withDefaultFonts,omitempty"`
+	// DefaultOpenAllTags expands all tags on load
+	DefaultOpenAllTags bool `json:"defaultOpenAllTags,omitempty"`
+	// TagsSorter is "alpha" to sort tags alphabetically
+	TagsSorter string `json:"tagsSorter,omitempty"`
+	// OperationsSorter is "alpha" or "method"
+	OperationsSorter string `json:"operationsSorter,omitempty"`
+}
+
+// ReferenceHTTPClient identifies a code sample client, e.g. {TargetKey: "shell", ClientKey: "curl"}
+type ReferenceHTTPClient struct {
+	TargetKey string `json:"targetKey"`
+	ClientKey string `json:"clientKey"`
+}
+
+// ReferencePathRouting holds the base path used by path based routing
+type ReferencePathRouting struct {
+	BasePath string `json:"basePath"`
+}
+
+// ReferenceServer is an OpenAPI server entry
+type ReferenceServer struct {
+	URL         string                             `json:"url"`
+	Description string                             `json:"description,omitempty"`
+	Variables   map[string]ReferenceServerVariable `json:"variables,omitempty"`
+}
+
+// ReferenceServerVariable is an OpenAPI server variable
+type ReferenceServerVariable struct {
+	Default     string   `json:"default"`
+	Enum        []string `json:"enum,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// Validate checks the enumerated options against the values accepted by Scalar,
+// which would otherwise silently fall back to its defaults
+func (c ReferenceConfig) Validate() error {
+	checks := []struct {
+		name    string
+		value   string
+		allowed []string
+	}{
+		{name: "theme", value: c.Theme, allowed: referenceThemes},
+		{name: "layout", value: c.Layout, allowed: referenceLayouts},
+		{name: "documentDownloadType", value: c.DocumentDownloadType, allowed: referenceDownloadTypes},
+		{name: "forceDarkModeState", value: c.ForceDarkModeState, allowed: referenceDarkModeStates},
+		{name: "tagsSorter", value: c.TagsSorter, allowed: referenceTagsSorters},
+		{name: "operationsSorter", value: c.OperationsSorter, allowed: referenceOperationSorters},
+	}
+
+	for _, check := range checks {
+		if check.value != "" && !slices.Contains(check.allowed, check.value) {
+			return fmt.Errorf("%w: unsupported %s %q", ErrInvalidReferenceConfig, check.name, check.value)
+		}
+	}
+
+	if hotKey := c.SearchHotKey; hotKey != "" && (len(hotKey) != 1 || hotKey[0] < 'a' || hotKey[0] > 'z') {
+		return fmt.Errorf("%w: searchHotKey must be a single letter from a to z, got %q", ErrInvalidReferenceConfig, hotKey)
+	}
+
+	switch clients := c.HiddenClients.(type) {
+	case nil, []string, map[string]bool, map[string][]string, map[string]any:
+	case bool:
+		if !clients {
+			return fmt.Errorf("%w: hiddenClients can only be true when it is a bool", ErrInvalidReferenceConfig)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported hiddenClients type %T", ErrInvalidReferenceConfig, clients)
+	}
+	return nil
+}
+
+// JSON returns the configuration as a JSON object that is safe to embed in a script.
+// encoding/json escapes <, > and & so the values can not close the script tag.
+func (c ReferenceConfig) JSON() (template.JS, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode reference config: %w", err)
+	}
+	return template.JS(data), nil
+}
+
+// defaultReferenceConfig returns the options used when WithReferenceConfig does not set them
+func defaultReferenceConfig() ReferenceConfig {
+	darkMode := true
+	return ReferenceConfig{DarkMode: &darkMode}
+}
+
+// WithReferenceConfig sets the options passed to the Scalar API reference. It replaces
+// any previous config, except that a nil DarkMode keeps the default dark mode.
+func WithReferenceConfig(config ReferenceConfig) Option {
+	return func(s *Scalar) error {
+		if err := config.Validate(); err != nil {
+			return err
+		}
+		if config.DarkMode == nil {
+			config.DarkMode = defaultReferenceConfig().DarkMode
+		}
+		s.config.Reference = config
+		return nil
+	}
+}
+
+// Reference sets the options passed to the Scalar API reference
+func (b *Builder) Reference(config ReferenceConfig) *Builder {
+	b.options = append(b.options, WithReferenceConfig(config))
+	return b
+}
@@ -0,0 +1,196 @@
+package goscalar
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ReferenceConfigValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      ReferenceConfig
+		expectError bool
+	}{
+		{
+			name:   "empty config",
+			config: ReferenceConfig{},
+		},
+		{
+			name: "valid config",
+			config: ReferenceConfig{
+				Theme:                ThemeMoon,
+				Layout:               LayoutClassic,
+				DocumentDownloadType: "json",
+				ForceDarkModeState:   "light",
+				SearchHotKey:         "k",
+				HiddenClients:        []string{"fetch"},
+				TagsSorter:           "alpha",
+				OperationsSorter:     "method",
+			},
+		},
+		{
+			name:   "hidden clients true",
+			config: ReferenceConfig{HiddenClients: true},
+		},
+		{
+			name:   "hidden clients map",
+			config: ReferenceConfig{HiddenClients: map[string][]string{"node": {"axios"}}},
+		},
+		{
+			name:        "unknown theme",
+			config:      ReferenceConfig{Theme: "dracula"},
+			expectError: true,
+		},
+		{
+			name:        "unknown layout",
+			config:      ReferenceConfig{Layout: "compact"},
+			expectError: true,
+		},
+		{
+			name:        "unknown download type",
+			config:      ReferenceConfig{DocumentDownloadType: "xml"},
+			expectError: true,
+		},
+		{
+			name:        "search hot key with two letters",
+			config:      ReferenceConfig{SearchHotKey: "ks"},
+			expectError: true,
+		},
+		{
+			name:        "search hot key upper case",
+			config:      ReferenceConfig{SearchHotKey: "K"},
+			expectError: true,
+		},
+		{
+			name:        "hidden clients false",
+			config:      ReferenceConfig{HiddenClients: false},
+			expectError: true,
+		},
+		{
+			name:        "hidden clients unsupported type",
+			config:      ReferenceConfig{HiddenClients: 42},
+			expectError: true,
+		},
+		{
+			name:        "unknown operations sorter",
+			config:      ReferenceConfig{OperationsSorter: "path"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+
+			if tt.expectError {
+				require.Error(t, err)
+				require.ErrorIs(t, err, ErrInvalidReferenceConfig)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_ReferenceConfigJSON(t *testing.T) {
+	showSidebar := false
+
+	tests := []struct {
+		name     string
+		config   ReferenceConfig
+		expected map[string]any
+	}{
+		{
+			name:     "zero values are omitted",
+			config:   ReferenceConfig{},
+			expected: map[string]any{},
+		},
+		{
+			name: "options use the Scalar names",
+			config: ReferenceConfig{
+				Theme:             ThemePurple,
+				ShowSidebar:       &showSidebar,
+				HideModels:        true,
+				SearchHotKey:      "k",
+				ProxyURL:          "https://proxy.scalar.com",
+				DefaultHTTPClient: &ReferenceHTTPClient{TargetKey: "shell", ClientKey: "curl"},
+				PathRouting:       &ReferencePathRouting{BasePath: "/docs"},
+				BaseServerURL:     "https://api.example.com",
+				CustomCSS:         ".scalar-app { color: red; }",
+			},
+			expected: map[string]any{
+				"theme":             "purple",
+				"showSidebar":       false,
+				"hideModels":        true,
+				"searchHotKey":      "k",
+				"proxyUrl":          "https://proxy.scalar.com",
+				"defaultHttpClient": map[string]any{"targetKey": "shell", "clientKey": "curl"},
+				"pathRouting":       map[string]any{"basePath": "/docs"},
+				"baseServerURL":     "https://api.example.com",
+				"customCss":         ".scalar-app { color: red; }",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.config.JSON()
+			require.NoError(t, err)
+
+			var decoded map[string]any
+			require.NoError(t, json.Unmarshal([]byte(result), &decoded))
+			require.Equal(t, tt.expected, decoded)
+		})
+	}
+}
+
+func Test_ReferenceConfigJSONEscaping(t *testing.T) {
+	config := ReferenceConfig{CustomCSS: "</script><script>alert(1)</script>"}
+
+	result, err := config.JSON()
+	require.NoError(t, err)
+	require.NotContains(t, string(result), "</script>")
+	require.NotContains(t, string(result), "<script>")
+}
+
+func Test_WithReferenceConfig(t *testing.T) {
+	validContent := `{"openapi": "3.0.0", "info": {"title": "Test API", "version": "1.0.0"}}`
+
+	t.Run("default keeps dark mode", func(t *testing.T) {
+		scalar, err := NewScalar(WithSpecContent(validContent))
+		require.NoError(t, err)
+		require.True(t, *scalar.config.Reference.DarkMode)
+	})
+
+	darkMode := false
+	tests := []struct {
+		name             string
+		config           ReferenceConfig
+		expectedDarkMode string
+	}{
+		{name: "unset dark mode keeps the default", config: ReferenceConfig{Theme: ThemeSaturn, Layout: LayoutClassic, HideDownloadButton: true}, expectedDarkMode: `"darkMode":true`},
+		{name: "dark mode can be disabled", config: ReferenceConfig{Theme: ThemeSaturn, Layout: LayoutClassic, HideDownloadButton: true, DarkMode: &darkMode}, expectedDarkMode: `"darkMode":false`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scalar, err := NewBuilder().
+				Content(validContent).
+				Reference(tt.config).
+				Build()
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, scalar.RenderDocs(&buf))
+
+			rendered := buf.String()
+			require.Contains(t, rendered, `"theme":"saturn"`)
+			require.Contains(t, rendered, `"layout":"classic"`)
+			require.Contains(t, rendered, `"hideDownloadButton":true`)
+			require.Contains(t, rendered, tt.expectedDarkMode)
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package goscalar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	// Refresh settings
+	defaultMinBackoff = time.Second
+)
+
+// RefreshStatus describes the state of the URL refresh started by WithRefresh
+type RefreshStatus struct {
+	Source              string    // Refreshed URL, with any password redacted
+	LastAttempt         time.Time // When the last fetch finished
+	LastSuccess         time.Time // When the source last answered with a valid or unchanged spec
+	LastError           error     // Error of the last fetch, nil when it succeeded
+	ConsecutiveFailures int       // Failed fetches since the last success
+	NextAttempt         time.Time // When the next fetch is scheduled
+	ETag                string    // ETag of the served copy, sent as If-None-Match
+	LastModified        string    // Last-Modified of the served copy, sent as If-Modified-Since
+}
+
+// Stale reports whether the last fetch failed, so an older copy is being served
+func (rs RefreshStatus) Stale() bool {
+	return rs.LastError != nil
+}
+
+// Available reports whether the source was ever fetched successfully. Until then a
+// placeholder spec is served.
+func (rs RefreshStatus) Available() bool {
+	return !rs.LastSuccess.IsZero()
+}
+
+// WithRefresh periodically re-fetches the spec loaded with WithURL, using the ETag and
+// Last-Modified returned by the source to skip unchanged copies. Failed fetches are retried
+// with exponential backoff while the last good spec keeps being served, and an unreachable
+// source no longer fails NewScalar: a placeholder is served until the first successful fetch.
+// Call Close to stop refreshing.
+func WithRefresh(interval time.Duration) Option {
+	return func(s *Scalar) error {
+		if interval <= 0 {
+			return fmt.Errorf("%w: %s", ErrInvalidRefresh, interval)
+		}
+		s.refreshInterval = interval
+		return nil
+	}
+}
+
+// WithRefreshBackoff sets the retry delays after a failed refresh. The delay starts at
+// minDelay and doubles on each consecutive failure up to maxDelay. By default it starts
+// at one second and is capped at the refresh interval.
+func WithRefreshBackoff(minDelay, maxDelay time.Duration) Option {
+	return func(s *Scalar) error {
+		if minDelay <= 0 || maxDelay < minDelay {
+			return fmt.Errorf("%w: backoff from %s to %s", ErrInvalidRefresh, minDelay, maxDelay)
+		}
+		s.minBackoff = minDelay
+		s.maxBackoff = maxDelay
+		return nil
+	}
+}
+
+// Refresh periodically re-fetches the spec URL
+func (b *Builder) Refresh(interval time.Duration) *Builder {
+	b.options = append(b.options, WithRefresh(interval))
+	return b
+}
+
+// RefreshBackoff sets the retry delays after a failed refresh
+func (b *Builder) RefreshBackoff(minDelay, maxDelay time.Duration) *Builder {
+	b.options = append(b.options, WithRefreshBackoff(minDelay, maxDelay))
+	return b
+}
+
+// RefreshStatus returns the state of the URL refresh. It is the zero value
+// when the spec was not loaded with WithURL.
+func (s *Scalar) RefreshStatus() RefreshStatus {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	return s.refreshStatus
+}
+
+// startRefresh re-fetches the spec URL until Close is called. The first fetch
+// is scheduled before returning, so the status is complete once NewScalar returns.
+func (s *Scalar) startRefresh() {
+	timer := time.NewTimer(s.scheduleRefresh())
+
+	s.startWorker(func(ctx context.Context) {
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				s.refreshURL(ctx)
+				timer.Reset(s.scheduleRefresh())
+			}
+		}
+	})
+}
+
+// scheduleRefresh returns the delay before the next fetch and records when it happens
+func (s *Scalar) scheduleRefresh() time.Duration {
+	minBackoff, maxBackoff := s.minBackoff, s.maxBackoff
+	if minBackoff <= 0 {
+		minBackoff = defaultMinBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = s.refreshInterval
+	}
+
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	delay := refreshDelay(s.refreshInterval, minBackoff, maxBackoff, s.refreshStatus.ConsecutiveFailures)
+	s.refreshStatus.NextAttempt = time.Now().Add(delay)
+	return delay
+}
+
+// refreshDelay returns the refresh interval, or the exponential backoff after failures
+func refreshDelay(interval, minBackoff, maxBackoff time.Duration, failures int) time.Duration {
+	if failures == 0 {
+		return interval
+	}
+
+	delay := minBackoff
+	for i := 1; i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+// refreshURL re-fetches the spec URL, keeping the last good spec when it fails
+func (s *Scalar) refreshURL(ctx context.Context) {
+	event := ReloadEvent{Source: redactURL(s.specURL)}
+
+	content, err := s.fetchSpecURL(ctx)
+	if err != nil {
+		// Fetches interrupted by Close are not failures of the source
+		if ctx.Err() != nil {
+			return
+		}
+		event.Err = fmt.Errorf("failed to refresh spec from URL: %w", err)
+	} else if content != "" {
+		event.Changed = s.swapContent(content)
+	}
+
+	event.Time = time.Now()
+	s.notifyReload(event)
+}
+
+// fetchSpecURL fetches and parses the spec URL, recording the outcome in the refresh
+// status. It returns an empty content when the source reports the served copy unchanged.
+func (s *Scalar) fetchSpecURL(ctx context.Context) (string, error) {
+	source := s.urlSource(s.specURL)
+
+	s.refreshMu.Lock()
+	validators := urlValidators{etag: s.refreshStatus.ETag, lastModified: s.refreshStatus.LastModified}
+	s.refreshMu.Unlock()
+
+	var content string
+	response, err := source.fetch(ctx, validators)
+	if err == nil && !response.notModified {
+		if err = s.verifySpec(ctx, response.content); err == nil {
+			content, err = parseSpecContent(string(response.content))
+		}
+		if err != nil {
+			loadErr := newLoadError(SourceKindURL, redactURL(s.specURL), response.content, err)
+			loadErr.StatusCode = response.statusCode
+			err = loadErr
+		}
+	}
+
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	status := &s.refreshStatus
+	status.Source = redactURL(s.specURL)
+	status.LastAttempt = time.Now()
+	status.LastError = err
+	if err != nil {
+		status.ConsecutiveFailures++
+		return "", err
+	}
+
+	// Validators are only kept for valid copies, so a broken one is fetched again
+	status.LastSuccess = status.LastAttempt
+	status.ConsecutiveFailures = 0
+	status.ETag = response.validators.etag
+	status.LastModified = response.validators.lastModified
+	return content, nil
+}
+
+// urlSource returns a source fetching a URL with the request settings of s
+func (s *Scalar) urlSource(rawURL string) URLSource {
+	return URLSource{
+		URL:        rawURL,
+		Client:     s.snapshot().HTTPClient,
+		Header:     s.urlHeader,
+		HeaderFunc: s.urlHeaderFunc,
+		UserAgent:  s.userAgent,
+		Policy:     s.fetchPolicy,
+	}
+}
+
+// unavailableSpec returns the placeholder spec served until the source is reachable
+func unavailableSpec(title string) string {
+	spec := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       title,
+			"version":     "unavailable",
+			"description": "The API specification is temporarily unavailable and will be shown once its source can be reached.",
+		},
+		"paths": map[string]any{},
+	}
+
+	content, _ := json.Marshal(spec)
+	return string(content)
+}
+
+// redactURL hides the password of a URL
+func redactURL(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return parsedURL.Redacted()
+}
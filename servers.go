@@ -0,0 +1,359 @@
+package goscalar
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+var (
+	// Errors
+	ErrInvalidServerRewrite = errors.New("invalid server rewrite")
+)
+
+// ServerMode selects how WithServerRewrite changes the servers of the spec
+type ServerMode int
+
+const (
+	// ServerReplace replaces the servers of the spec with the server of the request
+	ServerReplace ServerMode = iota
+	// ServerPrepend adds the server of the request before those of the spec, which stay selectable
+	ServerPrepend
+	// ServerVariables keeps the servers and sets the defaults of their variables from the request
+	ServerVariables
+)
+
+// ServerRewrite rewrites the servers of the spec for each request, so "Try it" calls
+// the environment serving the docs instead of the one the spec was generated for.
+// OpenAPI 3 root servers are rewritten, as well as Swagger 2.0 host, basePath and
+// schemes. Swagger 2.0 holds a single host, so prepending replaces it and only keeps
+// the other schemes, and it has no variables.
+type ServerRewrite struct {
+	Mode ServerMode
+
+	// Environments maps the hosts clients reach the docs at, with or without their
+	// port, to server URLs, e.g. "staging.example.com": "https://api.staging.example.com/v1".
+	// For Swagger 2.0 the URL path becomes the basePath, URLs without a path keep it.
+	Environments map[string]string
+
+	// FromRequest uses the scheme and host of the request for hosts missing from
+	// Environments, keeping the path of the first server or the basePath
+	FromRequest bool
+
+	// Hosts lists the request hosts FromRequest and ServerVariables may use, with or
+	// without their port, "*.example.com" matching subdomains. Clients choose the Host
+	// header, so both require it, and other hosts are served the spec as loaded.
+	Hosts []string
+
+	// Variables sets the defaults of server variables in ServerVariables mode. Values
+	// may contain the {scheme}, {host}, {hostname} and {port} placeholders of the
+	// request. Variables named scheme, protocol, host, hostname and port default to
+	// the request's otherwise.
+	Variables map[string]string
+}
+
+// WithServerRewrite rewrites the servers of the page and the spec to match the host
+// serving them. The host and scheme are the external ones when WithExternalURL or
+// WithTrustedProxies is set. Without them the Host header of the request is used,
+// so FromRequest and ServerVariables only use the hosts listed in Hosts.
+func WithServerRewrite(rewrite ServerRewrite) Option {
+	return func(s *Scalar) error {
+		normalized, err := rewrite.normalize()
+		if err != nil {
+			return err
+		}
+		s.config.ServerRewrite = normalized
+		return nil
+	}
+}
+
+// ServerRewrite rewrites the servers of the spec for each request
+func (b *Builder) ServerRewrite(rewrite ServerRewrite) *Builder {
+	b.options = append(b.options, WithServerRewrite(rewrite))
+	return b
+}
+
+// normalize validates the rewrite and returns a copy with lower case hosts
+func (r ServerRewrite) normalize() (*ServerRewrite, error) {
+	switch r.Mode {
+	case ServerReplace, ServerPrepend:
+		if len(r.Variables) > 0 {
+			return nil, fmt.Errorf("%w: variables only apply to ServerVariables", ErrInvalidServerRewrite)
+		}
+		if len(r.Environments) == 0 && !r.FromRequest {
+			return nil, fmt.Errorf("%w: set Environments or FromRequest", ErrInvalidServerRewrite)
+		}
+		if r.FromRequest != (len(r.Hosts) > 0) {
+			return nil, fmt.Errorf("%w: FromRequest and Hosts go together", ErrInvalidServerRewrite)
+		}
+	case ServerVariables:
+		if len(r.Environments) > 0 || r.FromRequest {
+			return nil, fmt.Errorf("%w: ServerVariables only sets variables", ErrInvalidServerRewrite)
+		}
+		if len(r.Hosts) == 0 {
+			return nil, fmt.Errorf("%w: ServerVariables requires Hosts", ErrInvalidServerRewrite)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown mode %d", ErrInvalidServerRewrite, r.Mode)
+	}
+
+	normalized := &ServerRewrite{Mode: r.Mode, FromRequest: r.FromRequest}
+	for _, host := range r.Hosts {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if name := strings.TrimPrefix(host, "*."); name == "" || strings.ContainsAny(name, "*/ ") {
+			return nil, fmt.Errorf("%w: invalid host %q", ErrInvalidServerRewrite, host)
+		}
+		normalized.Hosts = append(normalized.Hosts, host)
+	}
+	if len(r.Environments) > 0 {
+		normalized.Environments = make(map[string]string, len(r.Environments))
+	}
+	for host, rawURL := range r.Environments {
+		host = strings.ToLower(strings.TrimSpace(host))
+		server, err := url.Parse(rawURL)
+		if host == "" || err != nil || (server.Scheme != httpScheme && server.Scheme != httpsScheme) || server.Host == "" {
+			return nil, fmt.Errorf("%w: environment %q must map to an absolute http or https URL, got %q", ErrInvalidServerRewrite, host, rawURL)
+		}
+		normalized.Environments[host] = strings.TrimSuffix(rawURL, "/")
+	}
+	for name, value := range r.Variables {
+		if name == "" {
+			return nil, fmt.Errorf("%w: variable names cannot be empty", ErrInvalidServerRewrite)
+		}
+		if normalized.Variables == nil {
+			normalized.Variables = make(map[string]string, len(r.Variables))
+		}
+		normalized.Variables[name] = value
+	}
+	return normalized, nil
+}
+
+// serverTarget is the server a request rewrites the spec for
+type serverTarget struct {
+	key    string // Identifies the rewritten responses, empty keeps the spec as loaded
+	url    string // Server URL of an environment, empty for the request origin
+	scheme string
+	host   string
+}
+
+// serverTarget returns the server of a request, base being its external mount point if known
+func (c *Config) serverTarget(r *http.Request, base *url.URL) serverTarget {
+	rewrite := c.ServerRewrite
+	if rewrite == nil {
+		return serverTarget{}
+	}
+
+	target := serverTarget{scheme: httpScheme, host: strings.ToLower(r.Host)}
+	if r.TLS != nil {
+		target.scheme = httpsScheme
+	}
+	if base != nil {
+		target.scheme, target.host = base.Scheme, strings.ToLower(base.Host)
+	}
+	if target.host == "" {
+		return serverTarget{}
+	}
+	origin := target.scheme + "://" + target.host
+	hostname := target.host
+	if name, _, err := net.SplitHostPort(target.host); err == nil {
+		hostname = name
+	}
+
+	if rewrite.Mode == ServerVariables {
+		if !rewrite.allowsHost(target.host, hostname) {
+			return serverTarget{}
+		}
+		target.key = "variables " + origin
+		return target
+	}
+
+	for _, host := range []string{target.host, hostname} {
+		if server, ok := rewrite.Environments[host]; ok {
+			target.url = server
+			target.key = "environment " + server
+			return target
+		}
+	}
+	if rewrite.FromRequest && rewrite.allowsHost(target.host, hostname) {
+		target.key = "origin " + origin
+		return target
+	}
+	return serverTarget{}
+}
+
+// allowsHost reports whether Hosts lists a request host, given with and without its port
+func (r *ServerRewrite) allowsHost(host, hostname string) bool {
+	for _, allowed := range r.Hosts {
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok {
+			if strings.HasSuffix(hostname, suffix) && len(hostname) > len(suffix) {
+				return true
+			}
+		} else if allowed == host || allowed == hostname {
+			return true
+		}
+	}
+	return false
+}
+
+// withServers returns a copy of the configuration with the servers of its documents
+// rewritten for the target
+func (c *Config) withServers(target serverTarget) (*Config, error) {
+	rewritten := *c
+	var err error
+	if rewritten.Content, err = c.ServerRewrite.apply(c.Content, target); err != nil {
+		return nil, err
+	}
+
+	if len(c.Documents) > 0 {
+		rewritten.Documents = slices.Clone(c.Documents)
+		for i := range rewritten.Documents {
+			if rewritten.Documents[i].Content, err = c.ServerRewrite.apply(c.Documents[i].Content, target); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &rewritten, nil
+}
+
+// apply rewrites the servers of a normalized JSON document
+func (r *ServerRewrite) apply(content string, target serverTarget) (string, error) {
+	if content == "" {
+		return content, nil
+	}
+	tree, err := decodeTree(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode spec: %w", err)
+	}
+	root, ok := tree.(*object)
+	if !ok {
+		return content, nil
+	}
+
+	switch {
+	case root.str("swagger") != "":
+		r.applySwagger(root, target)
+	case r.Mode == ServerVariables:
+		r.applyVariables(root, target)
+	default:
+		r.applyServers(root, target)
+	}
+	return encodeTree(root)
+}
+
+// applyServers replaces or prepends the root servers of an OpenAPI 3 document
+func (r *ServerRewrite) applyServers(root *object, target serverTarget) {
+	servers, _ := root.values["servers"].([]any)
+
+	serverURL := target.url
+	if serverURL == "" {
+		serverURL = target.scheme + "://" + target.host + firstServerPath(servers)
+	}
+	server := newObject()
+	server.set("url", serverURL)
+
+	rewritten := []any{server}
+	if r.Mode == ServerPrepend {
+		for _, existing := range servers {
+			if obj, ok := existing.(*object); ok && obj.str("url") == serverURL {
+				continue
+			}
+			rewritten = append(rewritten, existing)
+		}
+	}
+	root.set("servers", rewritten)
+}
+
+// firstServerPath returns the path of the first server, without its trailing slash
+func firstServerPath(servers []any) string {
+	if len(servers) == 0 {
+		return ""
+	}
+	first, _ := servers[0].(*object)
+	parsed, err := url.Parse(first.str("url"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(parsed.Path, "/")
+}
+
+// applyVariables sets the defaults of the variables of the root servers
+func (r *ServerRewrite) applyVariables(root *object, target serverTarget) {
+	hostname, port := target.host, ""
+	if name, p, err := net.SplitHostPort(target.host); err == nil {
+		hostname, port = name, p
+	} else if target.scheme == httpsScheme {
+		port = "443"
+	} else {
+		port = "80"
+	}
+
+	placeholders := strings.NewReplacer("{scheme}", target.scheme, "{host}", target.host, "{hostname}", hostname, "{port}", port)
+	builtins := map[string]string{
+		"scheme":   target.scheme,
+		"protocol": target.scheme,
+		"host":     target.host,
+		"hostname": hostname,
+		"port":     port,
+	}
+
+	servers, _ := root.values["servers"].([]any)
+	for _, item := range servers {
+		server, _ := item.(*object)
+		variables := server.child("variables")
+		if variables == nil {
+			continue
+		}
+		for _, name := range variables.keys {
+			variable := variables.child(name)
+			if variable == nil {
+				continue
+			}
+			value, ok := r.Variables[name]
+			if ok {
+				value = placeholders.Replace(value)
+			} else if value, ok = builtins[name]; !ok {
+				continue
+			}
+
+			variable.set("default", value)
+			// The default must be one of the values of an enum
+			if enum, ok := variable.values["enum"].([]any); ok && !slices.Contains(enum, any(value)) {
+				variable.set("enum", append(enum, value))
+			}
+		}
+	}
+}
+
+// applySwagger sets the host, basePath and schemes of a Swagger 2.0 document
+func (r *ServerRewrite) applySwagger(root *object, target serverTarget) {
+	if r.Mode == ServerVariables {
+		return
+	}
+
+	scheme, host := target.scheme, target.host
+	if target.url != "" {
+		server, err := url.Parse(target.url)
+		if err != nil {
+			return
+		}
+		scheme, host = server.Scheme, server.Host
+		if path := strings.TrimSuffix(server.Path, "/"); path != "" {
+			root.set("basePath", path)
+		}
+	}
+	root.set("host", host)
+
+	schemes := []any{scheme}
+	if existing, ok := root.values["schemes"].([]any); ok && r.Mode == ServerPrepend {
+		for _, other := range existing {
+			if other != scheme {
+				schemes = append(schemes, other)
+			}
+		}
+	}
+	root.set("schemes", schemes)
+}
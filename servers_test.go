@@ -0,0 +1,259 @@
+package goscalar
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithServerRewrite(t *testing.T) {
+	validContent := `{"openapi": "3.0.0", "info": {"title": "Servers API", "version": "1.0.0"}}`
+
+	tests := []struct {
+		name        string
+		rewrite     ServerRewrite
+		expectedErr error
+	}{
+		{name: "from request", rewrite: ServerRewrite{FromRequest: true, Hosts: []string{"*.example.com"}}},
+		{name: "environments", rewrite: ServerRewrite{Mode: ServerPrepend, Environments: map[string]string{"Staging.example.com": "https://api.staging.example.com/v1"}}},
+		{name: "variables", rewrite: ServerRewrite{Mode: ServerVariables, Hosts: []string{"localhost:8080"}, Variables: map[string]string{"environment": "staging"}}},
+		{name: "from request without hosts", rewrite: ServerRewrite{FromRequest: true}, expectedErr: ErrInvalidServerRewrite},
+		{name: "hosts without from request", rewrite: ServerRewrite{Environments: map[string]string{"example.com": "https://api.example.com"}, Hosts: []string{"example.com"}}, expectedErr: ErrInvalidServerRewrite},
+		{name: "variables without hosts", rewrite: ServerRewrite{Mode: ServerVariables}, expectedErr: ErrInvalidServerRewrite},
+		{name: "invalid host", rewrite: ServerRewrite{FromRequest: true, Hosts: []string{"*"}}, expectedErr: ErrInvalidServerRewrite},
+		{name: "no server", rewrite: ServerRewrite{Mode: ServerReplace}, expectedErr: ErrInvalidServerRewrite},
+		{name: "relative environment URL", rewrite: ServerRewrite{Environments: map[string]string{"example.com": "/v1"}}, expectedErr: ErrInvalidServerRewrite},
+		{name: "variables when replacing", rewrite: ServerRewrite{FromRequest: true, Variables: map[string]string{"host": "example.com"}}, expectedErr: ErrInvalidServerRewrite},
+		{name: "environments with variables mode", rewrite: ServerRewrite{Mode: ServerVariables, FromRequest: true}, expectedErr: ErrInvalidServerRewrite},
+		{name: "unknown mode", rewrite: ServerRewrite{Mode: ServerMode(42), FromRequest: true}, expectedErr: ErrInvalidServerRewrite},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewBuilder().Content(validContent).ServerRewrite(tt.rewrite).Build()
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func Test_ServerRewrite(t *testing.T) {
+	openAPIContent := `{"openapi": "3.0.0", "info": {"title": "Servers API", "version": "1.0.0"}, "servers": [{"url": "https://api.example.com/v1"}, {"url": "https://sandbox.example.com/v1"}], "paths": {}}`
+	variablesContent := `{"openapi": "3.0.0", "info": {"title": "Servers API", "version": "1.0.0"}, "servers": [{"url": "{scheme}://{host}/{environment}", "variables": {"scheme": {"default": "https", "enum": ["https"]}, "host": {"default": "api.example.com"}, "environment": {"default": "prod"}, "region": {"default": "eu"}}}]}`
+	swaggerContent := `{"swagger": "2.0", "info": {"title": "Swag API", "version": "1.0.0"}, "host": "localhost:8080", "basePath": "/api/v1", "schemes": ["http", "https"], "paths": {}}`
+
+	environments := map[string]string{"staging.example.com": "https://api.staging.example.com/v2"}
+	hosts := []string{"*.example.com", "localhost"}
+
+	tests := []struct {
+		name     string
+		content  string
+		rewrite  ServerRewrite
+		options  []Option
+		host     string
+		headers  map[string]string
+		expected map[string]any
+	}{
+		{
+			name:     "replace from request",
+			content:  openAPIContent,
+			rewrite:  ServerRewrite{FromRequest: true, Hosts: hosts},
+			host:     "preview-42.example.com",
+			expected: map[string]any{"servers": []any{map[string]any{"url": "http://preview-42.example.com/v1"}}},
+		},
+		{
+			name:    "prepend environment",
+			content: openAPIContent,
+			rewrite: ServerRewrite{Mode: ServerPrepend, Environments: environments},
+			host:    "staging.example.com:8443",
+			expected: map[string]any{"servers": []any{
+				map[string]any{"url": "https://api.staging.example.com/v2"},
+				map[string]any{"url": "https://api.example.com/v1"},
+				map[string]any{"url": "https://sandbox.example.com/v1"},
+			}},
+		},
+		{
+			name:     "unknown host keeps the servers",
+			content:  openAPIContent,
+			rewrite:  ServerRewrite{Environments: environments},
+			host:     "evil.example.com",
+			expected: map[string]any{"servers": []any{map[string]any{"url": "https://api.example.com/v1"}, map[string]any{"url": "https://sandbox.example.com/v1"}}},
+		},
+		{
+			name:     "trusted proxy host and scheme",
+			content:  openAPIContent,
+			rewrite:  ServerRewrite{FromRequest: true, Hosts: hosts},
+			options:  []Option{WithTrustedProxies("192.0.2.0/24")},
+			host:     "internal:8080",
+			headers:  map[string]string{"X-Forwarded-Host": "docs.example.com", "X-Forwarded-Proto": "https"},
+			expected: map[string]any{"servers": []any{map[string]any{"url": "https://docs.example.com/v1"}}},
+		},
+		{
+			name:    "variables",
+			content: variablesContent,
+			rewrite: ServerRewrite{Mode: ServerVariables, Hosts: hosts, Variables: map[string]string{"environment": "{hostname}"}},
+			host:    "staging.example.com:8080",
+			expected: map[string]any{"servers": []any{map[string]any{
+				"url": "{scheme}://{host}/{environment}",
+				"variables": map[string]any{
+					"scheme":      map[string]any{"default": "http", "enum": []any{"https", "http"}},
+					"host":        map[string]any{"default": "staging.example.com:8080"},
+					"environment": map[string]any{"default": "staging.example.com"},
+					"region":      map[string]any{"default": "eu"},
+				},
+			}}},
+		},
+		{
+			name:     "unlisted host keeps the servers",
+			content:  openAPIContent,
+			rewrite:  ServerRewrite{FromRequest: true, Hosts: hosts},
+			host:     "evil.com",
+			expected: map[string]any{"servers": []any{map[string]any{"url": "https://api.example.com/v1"}, map[string]any{"url": "https://sandbox.example.com/v1"}}},
+		},
+		{
+			name:     "unlisted host keeps the variables",
+			content:  variablesContent,
+			rewrite:  ServerRewrite{Mode: ServerVariables, Hosts: []string{"docs.example.com"}},
+			host:     "evil.com",
+			expected: map[string]any{"servers": []any{map[string]any{"url": "{scheme}://{host}/{environment}", "variables": map[string]any{"scheme": map[string]any{"default": "https", "enum": []any{"https"}}, "host": map[string]any{"default": "api.example.com"}, "environment": map[string]any{"default": "prod"}, "region": map[string]any{"default": "eu"}}}}},
+		},
+		{
+			name:     "swagger replace from request",
+			content:  swaggerContent,
+			rewrite:  ServerRewrite{FromRequest: true, Hosts: hosts},
+			host:     "preview-42.example.com",
+			expected: map[string]any{"host": "preview-42.example.com", "basePath": "/api/v1", "schemes": []any{"http"}},
+		},
+		{
+			name:     "swagger prepend environment",
+			content:  swaggerContent,
+			rewrite:  ServerRewrite{Mode: ServerPrepend, Environments: environments},
+			host:     "staging.example.com",
+			expected: map[string]any{"host": "api.staging.example.com", "basePath": "/v2", "schemes": []any{"https", "http"}},
+		},
+		{
+			name:     "swagger variables",
+			content:  swaggerContent,
+			rewrite:  ServerRewrite{Mode: ServerVariables, Hosts: hosts},
+			host:     "staging.example.com",
+			expected: map[string]any{"host": "localhost:8080", "basePath": "/api/v1", "schemes": []any{"http", "https"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := append([]Option{WithSpecContent(tt.content), WithServerRewrite(tt.rewrite)}, tt.options...)
+			scalar, err := NewScalar(options...)
+			require.NoError(t, err)
+
+			request := func(target string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodGet, target, nil)
+				req.Host = tt.host
+				req.RemoteAddr = "192.0.2.1:1234"
+				for key, value := range tt.headers {
+					req.Header.Set(key, value)
+				}
+				rec := httptest.NewRecorder()
+				scalar.ServeHTTP(rec, req)
+				return rec
+			}
+
+			rec := request("/openapi.json")
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var spec map[string]any
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &spec))
+			for key, value := range tt.expected {
+				require.Equal(t, value, spec[key], key)
+			}
+
+			// The page embeds the same document
+			page := request("/")
+			require.Equal(t, http.StatusOK, page.Code)
+			require.Contains(t, page.Body.String(), escapeScriptJSON(rec.Body.Bytes()))
+		})
+	}
+
+	t.Run("responses vary by host", func(t *testing.T) {
+		scalar, err := NewScalar(WithSpecContent(openAPIContent), WithServerRewrite(ServerRewrite{FromRequest: true, Hosts: hosts}))
+		require.NoError(t, err)
+
+		etags := map[string]bool{}
+		for _, host := range []string{"a.example.com", "b.example.com", "a.example.com"} {
+			req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+			req.Host = host
+			rec := httptest.NewRecorder()
+			scalar.ServeHTTP(rec, req)
+			require.Contains(t, rec.Body.String(), "http://"+host+"/v1")
+			etags[rec.Header().Get("ETag")] = true
+		}
+		require.Len(t, etags, 2)
+		require.Equal(t, 2, scalar.responses().variants.len())
+
+		// RenderDocs has no request, it renders the spec as loaded
+		body, err := scalar.renderPage()
+		require.NoError(t, err)
+		require.Contains(t, string(body), "https://api.example.com/v1")
+	})
+
+	t.Run("least recently used variants are evicted", func(t *testing.T) {
+		scalar, err := NewScalar(WithSpecContent(openAPIContent), WithServerRewrite(ServerRewrite{FromRequest: true, Hosts: hosts}))
+		require.NoError(t, err)
+		cache := scalar.responses()
+		target := func(i int) serverTarget {
+			return cache.config.serverTarget(httptest.NewRequest(http.MethodGet, fmt.Sprintf("http://host-%d.example.com/", i), nil), nil)
+		}
+
+		first, err := cache.variant(target(0))
+		require.NoError(t, err)
+		second, err := cache.variant(target(1))
+		require.NoError(t, err)
+		for i := 2; i < 2*maxCachedVariants; i++ {
+			// The first host keeps being used, the others come and go
+			again, err := cache.variant(target(0))
+			require.NoError(t, err)
+			require.Same(t, first, again)
+
+			_, err = cache.variant(target(i))
+			require.NoError(t, err)
+		}
+		require.Equal(t, maxCachedVariants, cache.variants.len())
+
+		rebuilt, err := cache.variant(target(1))
+		require.NoError(t, err)
+		require.NotSame(t, second, rebuilt)
+	})
+
+	t.Run("rewritten responses are compressed when reused", func(t *testing.T) {
+		scalar, err := NewScalar(WithSpecContent(openAPIContent), WithServerRewrite(ServerRewrite{FromRequest: true, Hosts: hosts}))
+		require.NoError(t, err)
+
+		encodings := []string{}
+		for _, host := range []string{"a.example.com", "a.example.com", "localhost"} {
+			req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+			req.Host = host
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			scalar.ServeHTTP(rec, req)
+			require.Equal(t, http.StatusOK, rec.Code)
+			encodings = append(encodings, rec.Header().Get("Content-Encoding"))
+		}
+		require.Equal(t, []string{"", "gzip", ""}, encodings)
+
+		// The spec as loaded is compressed from the first response
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		// Without a host there is nothing to rewrite the servers for
+		req.Host = ""
+		scalar.ServeHTTP(rec, req)
+		require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	})
+}
@@ -0,0 +1,274 @@
+package goscalar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/swaggo/swag"
+)
+
+// ErrSourcesExhausted is returned when every source of a FallbackSource failed
+var ErrSourcesExhausted = errors.New("no spec source could be loaded")
+
+// Kinds of the built-in spec sources
+const (
+	SourceKindFile    = "file"
+	SourceKindFS      = "fs"
+	SourceKindURL     = "url"
+	SourceKindSwag    = "swag"
+	SourceKindContent = "content"
+)
+
+// SpecSource loads the raw JSON or YAML content of a specification
+type SpecSource interface {
+	Load(ctx context.Context) ([]byte, SourceMetadata, error)
+}
+
+// SourceMetadata describes where a loaded specification came from
+type SourceMetadata struct {
+	Kind         string    // One of the SourceKind constants, or a custom kind
+	Location     string    // Path or redacted URL, empty for in-memory sources
+	ETag         string    // Validator returned by URL sources
+	LastModified string    // Validator returned by URL sources
+	LoadedAt     time.Time // When the content was loaded
+	Fallback     int       // Index of the FallbackSource entry that was loaded
+	Skipped      []error   // Failures of the FallbackSource entries tried before it
+
+	files []string // Files read by FileSource, watched by WithWatch
+}
+
+// FileSource loads a specification from a local file, bundling relative $refs
+type FileSource struct {
+	Path string
+}
+
+// Load implements SpecSource
+func (f FileSource) Load(ctx context.Context) ([]byte, SourceMetadata, error) {
+	content, files, err := loadSpecFromFile(ctx, f.Path)
+	if err != nil {
+		return nil, SourceMetadata{}, newLoadError(SourceKindFile, f.Path, nil, err)
+	}
+	return []byte(content), SourceMetadata{Kind: SourceKindFile, Location: f.Path, LoadedAt: time.Now(), files: files}, nil
+}
+
+// FSSource loads a specification from a file system, bundling relative $refs
+type FSSource struct {
+	FS   fs.FS
+	Name string
+}
+
+// Load implements SpecSource
+func (f FSSource) Load(ctx context.Context) ([]byte, SourceMetadata, error) {
+	content, err := loadSpecFromFS(ctx, f.FS, f.Name)
+	if err != nil {
+		return nil, SourceMetadata{}, newLoadError(SourceKindFS, f.Name, nil, err)
+	}
+	return []byte(content), SourceMetadata{Kind: SourceKindFS, Location: f.Name, LoadedAt: time.Now()}, nil
+}
+
+// URLSource loads a specification from an HTTP/HTTPS URL
+type URLSource struct {
+	URL        string
+	Client     *http.Client // Defaults to a client with the default timeout
+	Header     http.Header  // Headers sent with the request, e.g. Authorization
+	HeaderFunc HeaderFunc   // Sets per-request headers, e.g. rotating tokens
+	UserAgent  string       // Defaults to go-scalar/1.0
+	Policy     *FetchPolicy // Restricts what the request may reach, nil allows any URL
+}
+
+// Load implements SpecSource
+func (u URLSource) Load(ctx context.Context) ([]byte, SourceMetadata, error) {
+	response, err := u.fetch(ctx, urlValidators{})
+	if err != nil {
+		return nil, SourceMetadata{}, err
+	}
+	return response.content, u.metadata(response), nil
+}
+
+// fetch validates the URL and fetches it with conditional headers
+func (u URLSource) fetch(ctx context.Context, validators urlValidators) (*urlResponse, error) {
+	if err := validateURL(u.URL); err != nil {
+		return nil, err
+	}
+
+	client := u.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	options := fetchOptions{setHeaders: u.setHeaders, validators: validators}
+	if u.Policy != nil {
+		policy, err := u.Policy.compile()
+		if err != nil {
+			return nil, err
+		}
+		if client, err = policy.client(client); err != nil {
+			return nil, err
+		}
+		// The policy transport is not reused by later fetches
+		defer client.CloseIdleConnections()
+		options.maxBodySize = policy.maxBodySize
+	}
+
+	response, err := fetchURL(ctx, u.URL, client, options)
+	if err != nil {
+		loadErr := newLoadError(SourceKindURL, redactURL(u.URL), nil, fmt.Errorf("failed to fetch from URL: %w", err))
+		var status *statusError
+		if errors.As(err, &status) {
+			loadErr.StatusCode = status.code
+			loadErr.Snippet = status.snippet
+		}
+		return nil, loadErr
+	}
+	return response, nil
+}
+
+// setHeaders adds the headers of the source to a request
+func (u URLSource) setHeaders(req *http.Request) error {
+	if u.UserAgent != "" {
+		req.Header.Set("User-Agent", u.UserAgent)
+	}
+	for key, values := range u.Header {
+		req.Header.Del(key)
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if u.HeaderFunc != nil {
+		if err := u.HeaderFunc(req.Context(), req.Header); err != nil {
+			return fmt.Errorf("failed to set request headers: %w", err)
+		}
+	}
+	return nil
+}
+
+// metadata describes a response of the source
+func (u URLSource) metadata(response *urlResponse) SourceMetadata {
+	return SourceMetadata{
+		Kind:         SourceKindURL,
+		Location:     redactURL(u.URL),
+		ETag:         response.validators.etag,
+		LastModified: response.validators.lastModified,
+		LoadedAt:     time.Now(),
+	}
+}
+
+// SwagSource loads a specification generated by swag
+type SwagSource struct {
+	Spec *swag.Spec
+}
+
+// Load implements SpecSource
+func (s SwagSource) Load(ctx context.Context) ([]byte, SourceMetadata, error) {
+	if s.Spec == nil {
+		return nil, SourceMetadata{}, ErrInvalidSpec
+	}
+	content := s.Spec.ReadDoc()
+	if content == "" {
+		return nil, SourceMetadata{}, ErrInvalidSpec
+	}
+	return []byte(content), SourceMetadata{Kind: SourceKindSwag, LoadedAt: time.Now()}, nil
+}
+
+// ContentSource serves a specification held in memory
+type ContentSource string
+
+// Load implements SpecSource
+func (c ContentSource) Load(ctx context.Context) ([]byte, SourceMetadata, error) {
+	content := strings.TrimSpace(string(c))
+	if content == "" {
+		return nil, SourceMetadata{}, ErrInvalidSpec
+	}
+	return []byte(content), SourceMetadata{Kind: SourceKindContent, LoadedAt: time.Now()}, nil
+}
+
+// FallbackSource tries its sources in order and returns the first valid spec,
+// for example a URL first and then an embedded copy
+type FallbackSource []SpecSource
+
+// Load implements SpecSource. The metadata is the one of the loaded source,
+// with Fallback and Skipped recording how it was reached.
+func (f FallbackSource) Load(ctx context.Context) ([]byte, SourceMetadata, error) {
+	var skipped []error
+	for i, source := range f {
+		if err := ctx.Err(); err != nil {
+			return nil, SourceMetadata{}, errors.Join(append(skipped, err)...)
+		}
+
+		content, metadata, err := loadSpecSource(ctx, source)
+		if err == nil {
+			metadata.Fallback = i
+			metadata.Skipped = skipped
+			return []byte(content), metadata, nil
+		}
+		skipped = append(skipped, fmt.Errorf("source %d: %w", i, err))
+	}
+	return nil, SourceMetadata{}, errors.Join(append([]error{ErrSourcesExhausted}, skipped...)...)
+}
+
+// loadSpecSource loads a source and normalizes its content
+func loadSpecSource(ctx context.Context, source SpecSource) (string, SourceMetadata, error) {
+	if source == nil {
+		return "", SourceMetadata{}, ErrSpecRequired
+	}
+
+	data, metadata, err := source.Load(ctx)
+	if err != nil {
+		return "", SourceMetadata{}, err
+	}
+
+	content, err := parseSpecContent(string(data))
+	if err != nil {
+		return "", SourceMetadata{}, newLoadError(metadata.Kind, metadata.Location, data, err)
+	}
+	return content, metadata, nil
+}
+
+// WithSpecSource loads the specification from any SpecSource
+func WithSpecSource(source SpecSource) Option {
+	return func(s *Scalar) error {
+		if err := s.loadFrom(source); err != nil {
+			return fmt.Errorf("failed to load spec from source: %w", err)
+		}
+		return nil
+	}
+}
+
+// SpecSource loads specification from any SpecSource
+func (b *Builder) SpecSource(source SpecSource) *Builder {
+	b.options = append(b.options, WithSpecSource(source))
+	return b
+}
+
+// loadFrom loads a source into the construction config
+func (s *Scalar) loadFrom(source SpecSource) error {
+	content, metadata, err := loadSpecSource(s.loadContext(), source)
+	if err != nil {
+		return err
+	}
+	s.config.Content = content
+	s.sourceMetadata = metadata
+	s.urlPending = false
+	s.mergePending = false
+	return nil
+}
+
+// loadContext returns the context loading options run under
+func (s *Scalar) loadContext() context.Context {
+	if s.loadCtx == nil {
+		return context.Background()
+	}
+	return s.loadCtx
+}
+
+// SourceMetadata describes where the specification was loaded from
+func (s *Scalar) SourceMetadata() SourceMetadata {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+	return s.sourceMetadata
+}
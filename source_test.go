@@ -0,0 +1,177 @@
+package goscalar
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/swaggo/swag"
+)
+
+// failingSource is a custom source that always fails
+type failingSource struct{ err error }
+
+func (f failingSource) Load(ctx context.Context) ([]byte, SourceMetadata, error) {
+	return nil, SourceMetadata{}, f.err
+}
+
+func Test_SpecSources(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "openapi.yaml")
+	require.NoError(t, os.WriteFile(filePath, []byte("openapi: 3.0.0\ninfo:\n  title: File\n"), 0644))
+
+	server := &specServer{}
+	server.set(1, false)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	tests := []struct {
+		name             string
+		source           SpecSource
+		expectedKind     string
+		expectedLocation string
+		expectedTitle    string
+		expectedErr      error
+	}{
+		{name: "file", source: FileSource{Path: filePath}, expectedKind: SourceKindFile, expectedLocation: filePath, expectedTitle: "File"},
+		{name: "fs", source: FSSource{FS: fstest.MapFS{"api.json": {Data: []byte(`{"openapi":"3.0.0","info":{"title":"FS"}}`)}}, Name: "api.json"}, expectedKind: SourceKindFS, expectedLocation: "api.json", expectedTitle: "FS"},
+		{name: "url", source: URLSource{URL: ts.URL}, expectedKind: SourceKindURL, expectedLocation: ts.URL, expectedTitle: "Version 1"},
+		{name: "swag", source: SwagSource{Spec: &swag.Spec{SwaggerTemplate: `{"swagger":"2.0","info":{"title":"Swag"}}`}}, expectedKind: SourceKindSwag, expectedTitle: "Swag"},
+		{name: "content", source: ContentSource(`{"openapi":"3.0.0","info":{"title":"Content"}}`), expectedKind: SourceKindContent, expectedTitle: "Content"},
+		{name: "empty content", source: ContentSource("  "), expectedErr: ErrInvalidSpec},
+		{name: "nil swag spec", source: SwagSource{}, expectedErr: ErrInvalidSpec},
+		{name: "invalid URL", source: URLSource{URL: "ftp://example.com"}, expectedErr: ErrUnsupportedScheme},
+		{name: "missing file", source: FileSource{Path: filepath.Join(dir, "missing.json")}, expectedErr: os.ErrNotExist},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, metadata, err := loadSpecSource(context.Background(), tt.source)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				require.Empty(t, content)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Contains(t, content, tt.expectedTitle)
+			require.Equal(t, tt.expectedKind, metadata.Kind)
+			require.Equal(t, tt.expectedLocation, metadata.Location)
+			require.False(t, metadata.LoadedAt.IsZero())
+		})
+	}
+
+	t.Run("url validators", func(t *testing.T) {
+		_, metadata, err := URLSource{URL: ts.URL}.Load(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, `"v1"`, metadata.ETag)
+		require.NotEmpty(t, metadata.LastModified)
+	})
+}
+
+func Test_FallbackSource(t *testing.T) {
+	server := &specServer{}
+	server.set(1, true)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	embedded := FSSource{FS: fstest.MapFS{"openapi.json": {Data: []byte(`{"openapi":"3.0.0","info":{"title":"Embedded"}}`)}}, Name: "openapi.json"}
+	custom := errors.New("custom failure")
+
+	t.Run("falls back to the embedded copy", func(t *testing.T) {
+		content, metadata, err := FallbackSource{URLSource{URL: ts.URL}, embedded}.Load(context.Background())
+		require.NoError(t, err)
+		require.Contains(t, string(content), "Embedded")
+		require.Equal(t, SourceKindFS, metadata.Kind)
+		require.Equal(t, 1, metadata.Fallback)
+		require.Len(t, metadata.Skipped, 1)
+		require.ErrorIs(t, metadata.Skipped[0], ErrHTTPRequest)
+	})
+
+	t.Run("first source wins when available", func(t *testing.T) {
+		server.set(2, false)
+		defer server.set(1, true)
+
+		content, metadata, err := FallbackSource{URLSource{URL: ts.URL}, embedded}.Load(context.Background())
+		require.NoError(t, err)
+		require.Contains(t, string(content), "Version 2")
+		require.Equal(t, SourceKindURL, metadata.Kind)
+		require.Zero(t, metadata.Fallback)
+		require.Empty(t, metadata.Skipped)
+	})
+
+	t.Run("invalid content falls back", func(t *testing.T) {
+		_, metadata, err := FallbackSource{ContentSource("<html>maintenance</html>"), embedded}.Load(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 1, metadata.Fallback)
+		require.ErrorIs(t, metadata.Skipped[0], ErrInvalidSpec)
+	})
+
+	t.Run("every source fails", func(t *testing.T) {
+		_, _, err := FallbackSource{failingSource{err: custom}, nil}.Load(context.Background())
+		require.ErrorIs(t, err, ErrSourcesExhausted)
+		require.ErrorIs(t, err, custom)
+		require.ErrorIs(t, err, ErrSpecRequired)
+		require.Contains(t, err.Error(), "source 0: custom failure")
+	})
+
+	t.Run("empty chain", func(t *testing.T) {
+		_, _, err := FallbackSource{}.Load(context.Background())
+		require.ErrorIs(t, err, ErrSourcesExhausted)
+	})
+
+	t.Run("canceled context stops the chain", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, _, err := FallbackSource{embedded}.Load(ctx)
+		require.ErrorIs(t, err, context.Canceled)
+		require.NotErrorIs(t, err, ErrSourcesExhausted)
+	})
+}
+
+func Test_WithSpecSource(t *testing.T) {
+	embedded := FSSource{FS: fstest.MapFS{"openapi.json": {Data: []byte(`{"openapi":"3.0.0","info":{"title":"Embedded"}}`)}}, Name: "openapi.json"}
+
+	t.Run("records the loaded source", func(t *testing.T) {
+		scalar, err := NewScalar(WithSpecSource(FallbackSource{failingSource{err: errors.New("offline")}, embedded}))
+		require.NoError(t, err)
+		defer scalar.Close()
+
+		require.Contains(t, serveSpec(t, scalar), "Embedded")
+		metadata := scalar.SourceMetadata()
+		require.Equal(t, SourceKindFS, metadata.Kind)
+		require.Equal(t, 1, metadata.Fallback)
+	})
+
+	t.Run("builder", func(t *testing.T) {
+		scalar, err := NewBuilder().SpecSource(embedded).Build()
+		require.NoError(t, err)
+		defer scalar.Close()
+		require.Equal(t, "openapi.json", scalar.SourceMetadata().Location)
+	})
+
+	t.Run("built-in options record their source", func(t *testing.T) {
+		scalar, err := NewScalar(WithSpecContent(`{"openapi":"3.0.0"}`))
+		require.NoError(t, err)
+		defer scalar.Close()
+		require.Equal(t, SourceKindContent, scalar.SourceMetadata().Kind)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		scalar, err := NewScalar(WithSpecSource(failingSource{err: errors.New("offline")}))
+		require.Error(t, err)
+		require.Nil(t, scalar)
+		require.Contains(t, err.Error(), "failed to load spec from source: offline")
+	})
+
+	t.Run("nil source", func(t *testing.T) {
+		_, err := NewScalar(WithSpecSource(nil))
+		require.ErrorIs(t, err, ErrSpecRequired)
+	})
+}
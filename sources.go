@@ -0,0 +1,164 @@
+package goscalar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+var (
+	// slugPattern matches the slugs accepted by WithSource, they are used in URLs
+	slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+	// Errors
+	ErrInvalidSource = errors.New("invalid source")
+)
+
+// Document is one of several API documents shown with Scalar's document switcher
+type Document struct {
+	Name    string // Title shown in the document switcher
+	Slug    string // Stable identifier, the raw spec is served under "<slug>/openapi.json"
+	Content string // Normalized JSON specification
+}
+
+// documentSource is a document as expected by the sources option of Scalar.createApiReference
+type documentSource struct {
+	Title   string          `json:"title"`
+	Slug    string          `json:"slug"`
+	Content json.RawMessage `json:"content"`
+}
+
+// WithSource adds a named document to the page, loaded with WithFile, WithURL, WithSpec or
+// WithSpecContent. Several sources are rendered with Scalar's document switcher, in the
+// order they were added, and each one serves its raw spec under "<slug>/openapi.json".
+// Sources are loaded once every option is applied, so WithHTTPClient and WithFetchPolicy
+// may come after them. Sources cannot be combined with a top-level spec, and may merge
+// services with WithMerge but cannot hold other sources.
+func WithSource(name, slug string, options ...Option) Option {
+	return func(s *Scalar) error {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return fmt.Errorf("%w: name cannot be empty", ErrInvalidSource)
+		}
+		if !slugPattern.MatchString(slug) || slug == strings.TrimSuffix(assetsPath, "/") {
+			return fmt.Errorf("%w: slug %q must be lower case letters, digits and dashes", ErrInvalidSource, slug)
+		}
+		for _, document := range s.config.Documents {
+			if document.Slug == slug {
+				return fmt.Errorf("%w: duplicate slug %q", ErrInvalidSource, slug)
+			}
+		}
+
+		s.config.Documents = append(s.config.Documents, Document{Name: name, Slug: slug})
+		s.pendingSources = append(s.pendingSources, pendingSource{slug: slug, options: options})
+		return nil
+	}
+}
+
+// pendingSource is a WithSource document loaded once every option is applied
+type pendingSource struct {
+	slug    string
+	options []Option
+}
+
+// loadSources loads the WithSource documents, returning the errors of every failing one
+func (s *Scalar) loadSources() []error {
+	pending := s.pendingSources
+	s.pendingSources = nil
+
+	var errs []error
+	for _, source := range pending {
+		content, err := loadSource(s, source.options)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to load source %q: %w", source.slug, err))
+			continue
+		}
+		for i := range s.config.Documents {
+			if s.config.Documents[i].Slug == source.slug {
+				s.config.Documents[i].Content = content
+			}
+		}
+	}
+	return errs
+}
+
+// loadSource loads a nested spec by applying its loading options to a separate
+// instance sharing the HTTP client, fetch policy and User-Agent of s. Request headers
+// are not shared, so credentials only reach the URL they were given for.
+func loadSource(s *Scalar, options []Option) (string, error) {
+	source := &Scalar{
+		config:      Config{HTTPClient: s.config.HTTPClient},
+		fetchPolicy: s.fetchPolicy,
+		userAgent:   s.userAgent,
+		loadCtx:     s.loadCtx,
+	}
+	for _, opt := range options {
+		if err := opt(source); err != nil {
+			return "", err
+		}
+	}
+	if source.watch || source.refreshInterval > 0 {
+		return "", fmt.Errorf("%w: watching and refreshing only apply to the top-level spec", ErrInvalidSource)
+	}
+	if len(source.pendingSources) > 0 {
+		return "", fmt.Errorf("%w: sources cannot be nested", ErrInvalidSource)
+	}
+	if source.verification.enabled() && source.specURL == "" {
+		return "", ErrVerifyRequiresURL
+	}
+	if err := source.loadMerge(); err != nil {
+		return "", err
+	}
+	if err := source.loadURL(); err != nil {
+		return "", err
+	}
+	if source.config.Content == "" {
+		return "", ErrSpecRequired
+	}
+	return source.config.Content, nil
+}
+
+// Source adds a named document to the page
+func (b *Builder) Source(name, slug string, options ...Option) *Builder {
+	b.options = append(b.options, WithSource(name, slug, options...))
+	return b
+}
+
+// document returns the spec served under the given slug. The empty slug is the
+// top-level spec, or the first source when the page shows several documents.
+func (c *Config) document(slug string) (string, bool) {
+	if slug == "" {
+		if len(c.Documents) > 0 {
+			return c.Documents[0].Content, true
+		}
+		return c.Content, true
+	}
+
+	for _, document := range c.Documents {
+		if document.Slug == slug {
+			return document.Content, true
+		}
+	}
+	return "", false
+}
+
+// sourcesJSON returns the documents as the escaped sources option of the page
+func (c *Config) sourcesJSON() (template.JS, error) {
+	sources := make([]documentSource, 0, len(c.Documents))
+	for _, document := range c.Documents {
+		sources = append(sources, documentSource{
+			Title:   document.Name,
+			Slug:    document.Slug,
+			Content: json.RawMessage(document.Content),
+		})
+	}
+
+	data, err := json.Marshal(sources)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode sources: %w", err)
+	}
+	return escapeScriptJSON(data), nil
+}
@@ -0,0 +1,207 @@
+package goscalar
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/swaggo/swag"
+)
+
+func Test_WithSource(t *testing.T) {
+	validContent := `{"openapi": "3.0.0", "info": {"title": "Test API", "version": "1.0.0"}}`
+
+	tests := []struct {
+		name        string
+		options     []Option
+		expectedErr error
+	}{
+		{
+			name:        "empty name",
+			options:     []Option{WithSource(" ", "public", WithSpecContent(validContent))},
+			expectedErr: ErrInvalidSource,
+		},
+		{
+			name:        "invalid slug",
+			options:     []Option{WithSource("Public", "Public API", WithSpecContent(validContent))},
+			expectedErr: ErrInvalidSource,
+		},
+		{
+			name:        "reserved slug",
+			options:     []Option{WithSource("Assets", "assets", WithSpecContent(validContent))},
+			expectedErr: ErrInvalidSource,
+		},
+		{
+			name: "duplicate slug",
+			options: []Option{
+				WithSource("Public", "public", WithSpecContent(validContent)),
+				WithSource("Public v2", "public", WithSpecContent(validContent)),
+			},
+			expectedErr: ErrInvalidSource,
+		},
+		{
+			name:        "missing spec",
+			options:     []Option{WithSource("Public", "public", WithTitle("Public"))},
+			expectedErr: ErrSpecRequired,
+		},
+		{
+			name:        "invalid spec",
+			options:     []Option{WithSource("Public", "public", WithSpecContent("{broken"))},
+			expectedErr: ErrInvalidSpec,
+		},
+		{
+			name: "combined with a top-level spec",
+			options: []Option{
+				WithSpecContent(validContent),
+				WithSource("Public", "public", WithSpecContent(validContent)),
+			},
+			expectedErr: ErrInvalidSource,
+		},
+		{
+			name: "nested source",
+			options: []Option{
+				WithSource("Public", "public", WithSource("Inner", "inner", WithSpecContent(validContent))),
+			},
+			expectedErr: ErrInvalidSource,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scalar, err := NewScalar(tt.options...)
+			require.ErrorIs(t, err, tt.expectedErr)
+			require.Nil(t, scalar)
+		})
+	}
+}
+
+func Test_Sources(t *testing.T) {
+	specFile := filepath.Join(t.TempDir(), "partner.yaml")
+	require.NoError(t, os.WriteFile(specFile, []byte("openapi: 3.0.0\ninfo:\n  title: Partner API\n  version: 1.0.0\n"), 0644))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"openapi": "3.0.0", "info": {"title": "Admin API", "version": "1.0.0"}}`))
+	}))
+	defer server.Close()
+
+	scalar, err := NewBuilder().
+		Title("Platform").
+		Source("Public", "public", WithSpecContent(`{"openapi": "3.0.0", "info": {"title": "Public API", "version": "1.0.0"}}`)).
+		Source("Partner", "partner", WithFile(specFile)).
+		Source("Admin", "admin", WithURL(server.URL)).
+		Source("Internal", "internal-v2", WithSpec(&swag.Spec{SwaggerTemplate: `{"swagger": "2.0", "info": {"title": "Internal API"}}`})).
+		Build()
+	require.NoError(t, err)
+	require.Len(t, scalar.config.Documents, 4)
+
+	t.Run("page lists the documents in order", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, scalar.RenderDocs(&buf))
+		rendered := buf.String()
+		require.NotContains(t, rendered, `id="api-reference-content"`)
+
+		var sources []struct {
+			Title   string         `json:"title"`
+			Slug    string         `json:"slug"`
+			Content map[string]any `json:"content"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(extractScriptBlock(t, rendered, "api-reference-sources")), &sources))
+		require.Len(t, sources, 4)
+
+		expected := []struct{ title, slug, specTitle string }{
+			{"Public", "public", "Public API"},
+			{"Partner", "partner", "Partner API"},
+			{"Admin", "admin", "Admin API"},
+			{"Internal", "internal-v2", "Internal API"},
+		}
+		for i, source := range sources {
+			require.Equal(t, expected[i].title, source.Title)
+			require.Equal(t, expected[i].slug, source.Slug)
+			require.Equal(t, expected[i].specTitle, source.Content["info"].(map[string]any)["title"])
+		}
+	})
+
+	tests := []struct {
+		name           string
+		target         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "source JSON", target: "/docs/partner/openapi.json", expectedStatus: http.StatusOK, expectedBody: `"title":"Partner API"`},
+		{name: "source YAML", target: "/docs/admin/openapi.yaml", expectedStatus: http.StatusOK, expectedBody: "title: Admin API"},
+		{name: "top-level spec is the first source", target: "/docs/openapi.json", expectedStatus: http.StatusOK, expectedBody: "Public API"},
+		{name: "unknown slug", target: "/docs/billing/openapi.json", expectedStatus: http.StatusNotFound},
+		{name: "unknown file", target: "/docs/public/swagger.json", expectedStatus: http.StatusNotFound},
+		{name: "nested path", target: "/docs/public/v1/openapi.json", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			scalar.Handler("/docs").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			require.True(t, strings.Contains(rec.Body.String(), tt.expectedBody))
+		})
+	}
+
+	t.Run("HTTP client set after the sources", func(t *testing.T) {
+		var requests atomic.Int32
+		client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requests.Add(1)
+			return http.DefaultTransport.RoundTrip(req)
+		})}
+
+		_, err := NewScalar(WithSource("Admin", "admin", WithURL(server.URL)), WithHTTPClient(client))
+		require.NoError(t, err)
+		require.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("merged source", func(t *testing.T) {
+		merged, err := NewScalar(WithSource("Platform", "platform", WithTitle("Platform API"), WithMerge(
+			MergeService{Name: "admin", PathPrefix: "/admin", Options: []Option{WithURL(server.URL)}},
+			MergeService{Name: "public", PathPrefix: "/public", Options: []Option{WithSpecContent(`{"openapi": "3.0.0", "info": {"title": "Public API", "version": "1.0.0"}}`)}},
+		)))
+		require.NoError(t, err)
+
+		content, ok := merged.config.document("platform")
+		require.True(t, ok)
+		require.Contains(t, content, `"title":"Platform API"`)
+	})
+
+	t.Run("User-Agent reaches the sources", func(t *testing.T) {
+		userAgents := make(chan string, 1)
+		agentServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userAgents <- r.UserAgent()
+			w.Write([]byte(`{"openapi": "3.0.0", "info": {"title": "Admin API", "version": "1.0.0"}}`))
+		}))
+		defer agentServer.Close()
+
+		_, err := NewScalar(WithUserAgent("docs/1.0"), WithSource("Admin", "admin", WithURL(agentServer.URL)))
+		require.NoError(t, err)
+		require.Equal(t, "docs/1.0", <-userAgents)
+	})
+}
+
+func Test_SourcesHostileInput(t *testing.T) {
+	scalar, err := NewScalar(
+		WithSource("</script><script>alert(1)</script>", "public",
+			WithSpecContent(`{"openapi":"3.0.0","info":{"title":"API","version":"1","description":"</script><img src=x>"}}`)),
+	)
+	require.NoError(t, err)
+
+	rendered := servePage(t, scalar)
+	require.Equal(t, 4, strings.Count(strings.ToLower(rendered), "<script"))
+	require.NotContains(t, rendered, "<img")
+
+	var sources []documentSource
+	require.NoError(t, json.Unmarshal([]byte(extractScriptBlock(t, rendered, "api-reference-sources")), &sources))
+	require.Equal(t, "</script><script>alert(1)</script>", sources[0].Title)
+}
@@ -0,0 +1,171 @@
+package goscalar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// specPath serves the spec in the format negotiated with the Accept header,
+	// relative to the mount prefix
+	specPath = "openapi"
+
+	// Values of the format query parameter
+	formatQueryJSON = "json"
+	formatQueryYAML = "yaml"
+
+	// JSON layouts of the spec, the document is served as loaded by default
+	layoutPretty  = "pretty"
+	layoutCompact = "compact"
+)
+
+// specMediaTypes lists the media types of each spec file, the one it is served as first
+var specMediaTypes = map[string][]string{
+	specJSONPath: {"application/json", "application/vnd.oai.openapi+json"},
+	specYAMLPath: {"application/yaml", "application/x-yaml", "text/yaml", "application/vnd.oai.openapi"},
+}
+
+// specQuery holds the query parameters of the spec endpoints
+type specQuery struct {
+	format   string // Spec file forced with ?format=, empty to negotiate it
+	layout   string // JSON layout chosen with ?pretty=, empty to keep the document as loaded
+	download bool   // Serve the spec as an attachment
+}
+
+// parseSpecQuery parses the format, pretty and download query parameters.
+// Parameters without a value, like ?download, are true.
+func parseSpecQuery(query url.Values) (specQuery, error) {
+	var parsed specQuery
+
+	switch format := strings.ToLower(query.Get("format")); format {
+	case "":
+	case formatQueryJSON:
+		parsed.format = specJSONPath
+	case formatQueryYAML, "yml":
+		parsed.format = specYAMLPath
+	default:
+		return specQuery{}, fmt.Errorf("unsupported format %q, use json or yaml", format)
+	}
+
+	if query.Has("pretty") {
+		pretty, err := queryBool(query, "pretty")
+		if err != nil {
+			return specQuery{}, err
+		}
+		parsed.layout = layoutCompact
+		if pretty {
+			parsed.layout = layoutPretty
+		}
+	}
+
+	if query.Has("download") {
+		download, err := queryBool(query, "download")
+		if err != nil {
+			return specQuery{}, err
+		}
+		parsed.download = download
+	}
+	return parsed, nil
+}
+
+// queryBool parses a boolean query parameter, an empty value is true
+func queryBool(query url.Values, name string) (bool, error) {
+	value := query.Get(name)
+	if value == "" {
+		return true, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s value %q, use true or false", name, value)
+	}
+	return parsed, nil
+}
+
+// negotiateSpecFormat picks the spec file from an Accept header, preferring JSON
+// when both formats have the same quality. It reports false when the header
+// accepts neither.
+func negotiateSpecFormat(accept string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return specJSONPath, true
+	}
+
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		mediaRange = strings.ToLower(strings.TrimSpace(mediaRange))
+		if mediaRange == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil {
+					continue
+				}
+				quality = parsed
+			}
+		}
+		qualities[mediaRange] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, file := range []string{specJSONPath, specYAMLPath} {
+		if quality := mediaQuality(qualities, specMediaTypes[file]); quality > bestQuality {
+			best, bestQuality = file, quality
+		}
+	}
+	return best, best != ""
+}
+
+// mediaQuality returns the quality of the best listed media type. Media ranges
+// apply to the first, the most specific one taking precedence (RFC 9110), the
+// others only count when the header names them.
+func mediaQuality(qualities map[string]float64, mediaTypes []string) float64 {
+	mainType, _, _ := strings.Cut(mediaTypes[0], "/")
+	best := 0.0
+	for _, mediaRange := range []string{mediaTypes[0], mainType + "/*", "*/*"} {
+		if quality, ok := qualities[mediaRange]; ok {
+			best = quality
+			break
+		}
+	}
+	for _, alias := range mediaTypes[1:] {
+		best = max(best, qualities[alias])
+	}
+	return best
+}
+
+// layoutJSON reformats a JSON document, only its insignificant whitespace changes
+func layoutJSON(content, layout string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch layout {
+	case layoutPretty:
+		err = json.Indent(&buf, []byte(content), "", "  ")
+	case layoutCompact:
+		err = json.Compact(&buf, []byte(content))
+	default:
+		return []byte(content), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to format JSON: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// setAttachment makes the response a download of the spec of the document with the given slug
+func setAttachment(w http.ResponseWriter, slug, file string) {
+	filename := file
+	if slug != "" {
+		filename = slug + "-" + file
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+}
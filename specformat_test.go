@@ -0,0 +1,110 @@
+package goscalar
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SpecEndpoint(t *testing.T) {
+	// Formatted on purpose, the spec is served as loaded unless a layout is asked for
+	validContent := "{\n    \"openapi\": \"3.0.0\",\n    \"info\": {\"title\": \"Negotiated API\", \"version\": \"1.0.0\", \"x-html\": \"<b>&</b>\", \"x-number\": 1.50}\n}"
+	sourceContent := `{"openapi": "3.0.0", "info": {"title": "Pets API", "version": "1.0.0"}}`
+
+	scalar, err := NewScalar(WithSpecContent(validContent))
+	require.NoError(t, err)
+	sources, err := NewScalar(WithSource("Pets", "pets", WithSpecContent(sourceContent)))
+	require.NoError(t, err)
+
+	yamlBody, err := jsonToYAML(validContent)
+	require.NoError(t, err)
+	var pretty, compact bytes.Buffer
+	require.NoError(t, json.Indent(&pretty, []byte(validContent), "", "  "))
+	require.NoError(t, json.Compact(&compact, []byte(validContent)))
+
+	tests := []struct {
+		name                string
+		scalar              *Scalar
+		target              string
+		accept              string
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+		expectedDisposition string
+	}{
+		{name: "no Accept", target: "/openapi", expectedStatus: http.StatusOK, expectedContentType: contentTypeJSON, expectedBody: validContent},
+		{name: "any type", target: "/openapi", accept: "*/*", expectedStatus: http.StatusOK, expectedContentType: contentTypeJSON, expectedBody: validContent},
+		{name: "JSON", target: "/openapi", accept: "application/json", expectedStatus: http.StatusOK, expectedContentType: contentTypeJSON, expectedBody: validContent},
+		{name: "YAML", target: "/openapi", accept: "application/yaml", expectedStatus: http.StatusOK, expectedContentType: contentTypeYAML, expectedBody: yamlBody},
+		{name: "OpenAPI YAML media type", target: "/openapi", accept: "application/vnd.oai.openapi", expectedStatus: http.StatusOK, expectedContentType: contentTypeYAML, expectedBody: yamlBody},
+		{name: "quality", target: "/openapi", accept: "application/json;q=0.5, text/yaml", expectedStatus: http.StatusOK, expectedContentType: contentTypeYAML, expectedBody: yamlBody},
+		{name: "specific range wins", target: "/openapi", accept: "application/*;q=0.9, application/json;q=0.1", expectedStatus: http.StatusOK, expectedContentType: contentTypeYAML, expectedBody: yamlBody},
+		{name: "not acceptable", target: "/openapi", accept: "text/html", expectedStatus: http.StatusNotAcceptable},
+		{name: "format override", target: "/openapi?format=yaml", accept: "application/json", expectedStatus: http.StatusOK, expectedContentType: contentTypeYAML, expectedBody: yamlBody},
+		{name: "unsupported format", target: "/openapi?format=xml", expectedStatus: http.StatusBadRequest},
+		{name: "pretty", target: "/openapi?pretty", expectedStatus: http.StatusOK, expectedContentType: contentTypeJSON, expectedBody: pretty.String()},
+		{name: "compact", target: "/openapi.json?pretty=false", expectedStatus: http.StatusOK, expectedContentType: contentTypeJSON, expectedBody: compact.String()},
+		{name: "layout of YAML", target: "/openapi.yaml?pretty=false", expectedStatus: http.StatusOK, expectedContentType: contentTypeYAML, expectedBody: yamlBody},
+		{name: "invalid pretty", target: "/openapi?pretty=maybe", expectedStatus: http.StatusBadRequest},
+		{
+			name:                "download",
+			target:              "/openapi?format=yaml&download",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: contentTypeYAML,
+			expectedBody:        yamlBody,
+			expectedDisposition: `attachment; filename=openapi.yaml`,
+		},
+		{
+			name:                "source download",
+			scalar:              sources,
+			target:              "/pets/openapi?download=true",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: contentTypeJSON,
+			expectedBody:        sourceContent,
+			expectedDisposition: `attachment; filename=pets-openapi.json`,
+		},
+		{name: "unknown source", scalar: sources, target: "/cats/openapi", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.scalar == nil {
+				tt.scalar = scalar
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			tt.scalar.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			require.Equal(t, tt.expectedContentType, rec.Header().Get("Content-Type"))
+			require.Equal(t, tt.expectedBody, rec.Body.String())
+			require.Equal(t, tt.expectedDisposition, rec.Header().Get("Content-Disposition"))
+		})
+	}
+
+	t.Run("same document as the page", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		scalar.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi", nil))
+
+		page, err := scalar.renderPage()
+		require.NoError(t, err)
+		require.Contains(t, string(page), string(escapeScriptJSON(rec.Body.Bytes())))
+	})
+
+	t.Run("negotiated responses vary by Accept", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		scalar.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi", nil))
+		require.Equal(t, []string{"Accept", "Accept-Encoding"}, rec.Header().Values("Vary"))
+	})
+}
@@ -0,0 +1,80 @@
+package goscalar
+
+import (
+	"context"
+	"slices"
+)
+
+// Update reconfigures a running Scalar, see UpdateContext
+func (s *Scalar) Update(options ...Option) error {
+	return s.UpdateContext(context.Background(), options...)
+}
+
+// UpdateContext reconfigures a running Scalar with options, loading specs under ctx.
+// The options replace the ones the Scalar was created with: they are applied to a
+// fresh configuration and validated exactly like NewScalar does, so settings that
+// are left out go back to their defaults. On error nothing changes. Otherwise the
+// new configuration is swapped in atomically, so every request sees either the old
+// or the new one, and the WithWatch and WithRefresh workers are restarted for it.
+// It must not be called from a reload callback.
+func (s *Scalar) UpdateContext(ctx context.Context, options ...Option) error {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+
+	return s.reconfigure(ctx, slices.Clone(options))
+}
+
+// Reload loads the spec again from its sources by applying the options of the
+// served configuration again, then swaps it in like UpdateContext. On error the
+// last good spec keeps being served. It must not be called from a reload callback.
+func (s *Scalar) Reload(ctx context.Context) error {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+
+	return s.reconfigure(ctx, s.options)
+}
+
+// reconfigure builds and validates a configuration, then swaps it in
+func (s *Scalar) reconfigure(ctx context.Context, options []Option) error {
+	// The placeholder spec is only served at startup, later a good spec is kept instead
+	next, err := configure(ctx, options, false)
+	if err != nil {
+		return err
+	}
+
+	// Workers read the settings, so they are stopped before replacing them
+	s.stopWorkers()
+
+	s.stateMu.Lock()
+	s.config = next.config
+	s.filePath = next.filePath
+	s.watchFiles = next.watchFiles
+	s.watch = next.watch
+	s.interval = next.interval
+	s.onReload = next.onReload
+	s.specURL = next.specURL
+	s.urlHeader = next.urlHeader
+	s.urlHeaderFunc = next.urlHeaderFunc
+	s.userAgent = next.userAgent
+	s.fetchPolicy = next.fetchPolicy
+	s.verification = next.verification
+	s.refreshInterval = next.refreshInterval
+	s.minBackoff = next.minBackoff
+	s.maxBackoff = next.maxBackoff
+	s.mergeWarnings = next.mergeWarnings
+	s.sourceMetadata = next.sourceMetadata
+	s.options = options
+	s.stateMu.Unlock()
+
+	s.refreshMu.Lock()
+	s.refreshStatus = next.refreshStatus
+	s.refreshMu.Unlock()
+
+	current := next.config
+	s.current.Store(&current)
+
+	if s.closed {
+		return nil
+	}
+	return s.start()
+}
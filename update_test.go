@@ -0,0 +1,266 @@
+package goscalar
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// versionedSpec returns a spec whose title and version identify it
+func versionedSpec(version int) string {
+	return fmt.Sprintf(`{"openapi": "3.0.0", "info": {"title": "Version %d", "version": "%d"}}`, version, version)
+}
+
+func Test_Update(t *testing.T) {
+	tests := []struct {
+		name            string
+		options         []Option
+		expectedErr     error
+		expectedTitle   string
+		expectedContent string
+	}{
+		{
+			name:            "new spec and title",
+			options:         []Option{WithTitle("Updated"), WithSpecContent(versionedSpec(2))},
+			expectedTitle:   "Updated",
+			expectedContent: versionedSpec(2),
+		},
+		{
+			name:            "left out settings go back to their defaults",
+			options:         []Option{WithSpecContent(versionedSpec(2))},
+			expectedTitle:   defaultTitle,
+			expectedContent: versionedSpec(2),
+		},
+		{name: "invalid spec", options: []Option{WithTitle("Updated"), WithSpecContent("{\"openapi\": ]}")}, expectedErr: ErrInvalidSpec},
+		{name: "invalid title", options: []Option{WithTitle(""), WithSpecContent(versionedSpec(2))}, expectedErr: ErrInvalidTitle},
+		{name: "no spec", options: []Option{WithTitle("Updated")}, expectedErr: ErrSpecRequired},
+		{name: "refresh without URL", options: []Option{WithSpecContent(versionedSpec(2)), WithRefresh(time.Minute)}, expectedErr: ErrRefreshRequiresURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scalar, err := NewScalar(WithTitle("Original"), WithSpecContent(versionedSpec(1)))
+			require.NoError(t, err)
+			defer scalar.Close()
+
+			err = scalar.Update(tt.options...)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				require.Equal(t, "Original", scalar.snapshot().Title)
+				require.Equal(t, versionedSpec(1), serveSpec(t, scalar))
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedTitle, scalar.snapshot().Title)
+			require.Equal(t, tt.expectedContent, serveSpec(t, scalar))
+		})
+	}
+
+	t.Run("metadata", func(t *testing.T) {
+		dir := writeSpecFiles(t, map[string]string{"openapi.json": versionedSpec(1)})
+
+		scalar, err := NewScalar(WithSpecContent(versionedSpec(1)))
+		require.NoError(t, err)
+		defer scalar.Close()
+		require.Equal(t, SourceKindContent, scalar.SourceMetadata().Kind)
+
+		require.NoError(t, scalar.Update(WithFile(filepath.Join(dir, "openapi.json"))))
+		require.Equal(t, SourceKindFile, scalar.SourceMetadata().Kind)
+	})
+
+	t.Run("watching follows the new file", func(t *testing.T) {
+		dir := writeSpecFiles(t, map[string]string{"first.json": versionedSpec(1), "second.json": versionedSpec(2)})
+
+		events := make(chan ReloadEvent, 10)
+		callback := WithReloadCallback(func(event ReloadEvent) {
+			select {
+			case events <- event:
+			default:
+			}
+		})
+
+		scalar, err := NewScalar(WithFile(filepath.Join(dir, "first.json")), WithWatch(), callback)
+		require.NoError(t, err)
+		defer scalar.Close()
+
+		second := filepath.Join(dir, "second.json")
+		require.NoError(t, scalar.Update(WithFile(second), WithWatch(), callback))
+		require.Equal(t, versionedSpec(2), serveSpec(t, scalar))
+
+		require.NoError(t, os.WriteFile(second, []byte(versionedSpec(3)), 0644))
+		waitReloadEvent(t, events, func(event ReloadEvent) bool { return event.Changed })
+		require.Equal(t, versionedSpec(3), serveSpec(t, scalar))
+	})
+
+	t.Run("closed instances are not restarted", func(t *testing.T) {
+		dir := writeSpecFiles(t, map[string]string{"openapi.json": versionedSpec(1)})
+		path := filepath.Join(dir, "openapi.json")
+
+		scalar, err := NewScalar(WithFile(path), WithWatch())
+		require.NoError(t, err)
+		require.NoError(t, scalar.Close())
+
+		require.NoError(t, scalar.Update(WithFile(path), WithWatch()))
+		require.Nil(t, scalar.cancel)
+		require.NoError(t, scalar.Close())
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		scalar, err := NewScalar(WithSpecContent(versionedSpec(1)))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		dir := writeSpecFiles(t, map[string]string{"openapi.json": versionedSpec(2)})
+		err = scalar.UpdateContext(ctx, WithFile(filepath.Join(dir, "openapi.json")))
+		require.ErrorIs(t, err, context.Canceled)
+		require.Equal(t, versionedSpec(1), serveSpec(t, scalar))
+	})
+}
+
+func Test_Reload(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		dir := writeSpecFiles(t, map[string]string{"openapi.json": versionedSpec(1)})
+		path := filepath.Join(dir, "openapi.json")
+
+		scalar, err := NewScalar(WithTitle("Pets"), WithFile(path))
+		require.NoError(t, err)
+		defer scalar.Close()
+
+		require.NoError(t, os.WriteFile(path, []byte(versionedSpec(2)), 0644))
+		require.NoError(t, scalar.Reload(context.Background()))
+		require.Equal(t, versionedSpec(2), serveSpec(t, scalar))
+		require.Equal(t, "Pets", scalar.snapshot().Title)
+
+		// A broken file keeps the last good spec
+		require.NoError(t, os.WriteFile(path, []byte("{\"openapi\": ]}"), 0644))
+		require.ErrorIs(t, scalar.Reload(context.Background()), ErrInvalidSpec)
+		require.Equal(t, versionedSpec(2), serveSpec(t, scalar))
+	})
+
+	t.Run("reloads updated options", func(t *testing.T) {
+		dir := writeSpecFiles(t, map[string]string{"openapi.json": versionedSpec(1)})
+		path := filepath.Join(dir, "openapi.json")
+
+		scalar, err := NewScalar(WithSpecContent(versionedSpec(1)))
+		require.NoError(t, err)
+		defer scalar.Close()
+
+		require.NoError(t, scalar.Update(WithFile(path)))
+		require.NoError(t, os.WriteFile(path, []byte(versionedSpec(2)), 0644))
+		require.NoError(t, scalar.Reload(context.Background()))
+		require.Equal(t, versionedSpec(2), serveSpec(t, scalar))
+	})
+
+	t.Run("unreachable URL keeps the last good spec", func(t *testing.T) {
+		server := &specServer{version: 1}
+		ts := httptest.NewServer(server)
+		defer ts.Close()
+
+		scalar, err := NewScalar(WithURL(ts.URL), WithRefresh(time.Hour))
+		require.NoError(t, err)
+		defer scalar.Close()
+		before := serveSpec(t, scalar)
+
+		server.set(2, true)
+		require.ErrorIs(t, scalar.Reload(context.Background()), ErrHTTPRequest)
+		require.Equal(t, before, serveSpec(t, scalar))
+		require.True(t, scalar.RefreshStatus().Available())
+
+		server.set(2, false)
+		require.NoError(t, scalar.Reload(context.Background()))
+		require.NotEqual(t, before, serveSpec(t, scalar))
+		require.False(t, scalar.RefreshStatus().Stale())
+	})
+}
+
+func Test_UpdateConcurrency(t *testing.T) {
+	titlePattern := regexp.MustCompile(`<title>Title (\d+)</title>`)
+	versionPattern := regexp.MustCompile(`"version": "(\d+)"`)
+
+	configuration := func(version int) []Option {
+		return []Option{WithTitle(fmt.Sprintf("Title %d", version)), WithSpecContent(versionedSpec(version))}
+	}
+
+	scalar, err := NewScalar(configuration(0)...)
+	require.NoError(t, err)
+	defer scalar.Close()
+
+	const iterations = 50
+
+	// Every goroutine waits for the others, so readers and writers overlap
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+
+	// Writers keep swapping configurations in
+	for writer := 0; writer < 2; writer++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for version := 1; version <= iterations; version++ {
+				if err := scalar.Update(configuration(version)...); err != nil {
+					errs <- err
+					return
+				}
+				if err := scalar.Reload(context.Background()); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	// Readers check every page shows the title and spec of the same configuration
+	for reader := 0; reader < 4; reader++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for i := 0; i < iterations; i++ {
+				var buf bytes.Buffer
+				if err := scalar.RenderDocs(&buf); err != nil {
+					errs <- err
+					return
+				}
+				title := titlePattern.FindStringSubmatch(buf.String())
+				version := versionPattern.FindStringSubmatch(buf.String())
+				if title == nil || version == nil || title[1] != version[1] {
+					errs <- fmt.Errorf("inconsistent page: title %v, spec version %v", title, version)
+					return
+				}
+
+				rec := httptest.NewRecorder()
+				scalar.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+				if rec.Code != http.StatusOK {
+					errs <- fmt.Errorf("unexpected status %d", rec.Code)
+					return
+				}
+				scalar.SourceMetadata()
+				scalar.MergeWarnings()
+				scalar.RefreshStatus()
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
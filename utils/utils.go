@@ -1,8 +1,8 @@
 package utils
 
 import (
+	"html/template"
 	"io/fs"
-	"text/template"
 )
 
 func ParseTemplateFromFS(fsys fs.FS, patterns ...string) (*template.Template, error) {
@@ -0,0 +1,286 @@
+package goscalar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// Watch settings
+	defaultWatchInterval = time.Second
+	watchDebounce        = 100 * time.Millisecond
+)
+
+// ReloadEvent describes the outcome of a background spec reload
+type ReloadEvent struct {
+	Source  string    // Location the spec was reloaded from
+	Changed bool      // Whether the served spec changed
+	Err     error     // Reload error, the last good spec keeps being served
+	Time    time.Time // When the reload finished
+}
+
+// WithWatch re-reads and re-validates the file loaded with WithFile whenever it, or a
+// file its $refs bundle, changes, atomically swapping the served spec. File system
+// events are used when available, falling back to polling. Failed reloads keep the
+// last good spec. Call Close to stop watching.
+func WithWatch() Option {
+	return func(s *Scalar) error {
+		s.watch = true
+		if s.interval <= 0 {
+			s.interval = defaultWatchInterval
+		}
+		return nil
+	}
+}
+
+// WithReloadCallback sets a callback observing every background reload, successful or not.
+// It is called from the watching goroutine, so it must not block.
+func WithReloadCallback(callback func(ReloadEvent)) Option {
+	return func(s *Scalar) error {
+		s.onReload = callback
+		return nil
+	}
+}
+
+// Watch reloads the spec file when it changes
+func (b *Builder) Watch() *Builder {
+	b.options = append(b.options, WithWatch())
+	return b
+}
+
+// OnReload sets a callback observing background reloads
+func (b *Builder) OnReload(callback func(ReloadEvent)) *Builder {
+	b.options = append(b.options, WithReloadCallback(callback))
+	return b
+}
+
+// startWatch starts watching the spec file loaded with WithFile and the files its $refs
+// bundle. The watch is set up before returning, so changes made right after NewScalar
+// returns are not missed.
+func (s *Scalar) startWatch() error {
+	fileURL, err := normalizeFileURL(s.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to normalize file URL: %w", err)
+	}
+	path := filepath.Clean(strings.TrimPrefix(fileURL, filePrefix))
+
+	files := map[string]bool{path: true}
+	for _, file := range s.watchFiles {
+		files[file] = true
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		s.startPolling(path, files)
+		return nil
+	}
+
+	dirs := map[string]bool{}
+	if _, err := watchDirs(watcher, dirs, files); err != nil {
+		watcher.Close()
+		s.startPolling(path, files)
+		return nil
+	}
+
+	s.startWorker(func(ctx context.Context) {
+		s.watchEvents(ctx, watcher, dirs, path, files)
+	})
+	return nil
+}
+
+// watchDirs watches the directories of the files and stops watching the others.
+// Directories are watched because editors and generators replace files by renaming.
+// The nearest existing parent of a missing directory is watched until it is created.
+// It reports whether directories were added.
+func watchDirs(watcher *fsnotify.Watcher, dirs, files map[string]bool) (bool, error) {
+	needed := map[string]bool{}
+	for file := range files {
+		dir := filepath.Dir(file)
+		for {
+			if _, err := os.Stat(dir); !errors.Is(err, fs.ErrNotExist) || filepath.Dir(dir) == dir {
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+		needed[dir] = true
+	}
+
+	for dir := range dirs {
+		if !needed[dir] {
+			watcher.Remove(dir)
+			delete(dirs, dir)
+		}
+	}
+
+	added := false
+	var errs []error
+	for dir := range needed {
+		if dirs[dir] {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dirs[dir] = true
+		added = true
+	}
+	return added, errors.Join(errs...)
+}
+
+// affectsFiles reports whether a file system event is about one of the files or one
+// of their directories
+func affectsFiles(files map[string]bool, name string) bool {
+	name = filepath.Clean(name)
+	if files[name] {
+		return true
+	}
+	for file := range files {
+		if strings.HasPrefix(file, name+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchEvents reloads the spec file on file system events until the context is canceled
+func (s *Scalar) watchEvents(ctx context.Context, watcher *fsnotify.Watcher, dirs map[string]bool, path string, files map[string]bool) {
+	defer watcher.Close()
+
+	// Writes usually come as several events, so reloads are debounced
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if affectsFiles(files, event.Name) && !event.Has(fsnotify.Chmod) {
+				debounce.Reset(watchDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			s.notifyReload(ReloadEvent{Source: path, Err: fmt.Errorf("failed to watch file: %w", err), Time: time.Now()})
+		case <-debounce.C:
+			s.reloadFile(ctx, path, files)
+			// Files created before their new directory was watched are only seen by reading them again
+			added, err := watchDirs(watcher, dirs, files)
+			if err != nil {
+				s.notifyReload(ReloadEvent{Source: path, Err: fmt.Errorf("failed to watch file: %w", err), Time: time.Now()})
+			}
+			if added {
+				debounce.Reset(watchDebounce)
+			}
+		}
+	}
+}
+
+// fileStat is the result of os.Stat
+type fileStat struct {
+	info os.FileInfo
+	err  error
+}
+
+// statFiles stats the files, reusing the results already known
+func statFiles(files map[string]bool, known map[string]fileStat) map[string]fileStat {
+	stats := make(map[string]fileStat, len(files))
+	for file := range files {
+		if stat, ok := known[file]; ok {
+			stats[file] = stat
+			continue
+		}
+		info, err := os.Stat(file)
+		stats[file] = fileStat{info: info, err: err}
+	}
+	return stats
+}
+
+// startPolling reloads the spec file when the size or modification time of one of the
+// watched files changes
+func (s *Scalar) startPolling(path string, files map[string]bool) {
+	last := statFiles(files, nil)
+
+	s.startWorker(func(ctx context.Context) {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				current := statFiles(files, nil)
+				changed := false
+				for file, stat := range current {
+					before := last[file]
+					if fileChanged(before.info, before.err, stat.info, stat.err) {
+						changed = true
+						break
+					}
+				}
+				if !changed {
+					continue
+				}
+				s.reloadFile(ctx, path, files)
+				// Files added by the reload are compared from now on
+				last = statFiles(files, current)
+			}
+		}
+	})
+}
+
+// fileChanged compares two os.Stat results
+func fileChanged(before os.FileInfo, beforeErr error, after os.FileInfo, afterErr error) bool {
+	if beforeErr != nil || afterErr != nil {
+		return (beforeErr == nil) != (afterErr == nil)
+	}
+	return before.Size() != after.Size() || !before.ModTime().Equal(after.ModTime())
+}
+
+// reloadFile re-reads the spec file, keeping the last good spec when it is invalid.
+// The watched files become those the reload read. A failed reload adds them instead,
+// so creating a missing referenced file triggers the next reload.
+func (s *Scalar) reloadFile(ctx context.Context, path string, files map[string]bool) {
+	event := ReloadEvent{Source: path}
+
+	content, read, err := loadSpecFromFile(ctx, path)
+	if err != nil {
+		// Reads interrupted by Close are not failures of the file
+		if ctx.Err() != nil {
+			return
+		}
+		event.Err = fmt.Errorf("failed to reload spec from file: %w", err)
+	} else {
+		event.Changed = s.swapContent(content)
+		clear(files)
+	}
+	for _, file := range read {
+		files[file] = true
+	}
+	files[path] = true
+
+	event.Time = time.Now()
+	s.notifyReload(event)
+}
+
+// notifyReload reports a reload to the callback, if any
+func (s *Scalar) notifyReload(event ReloadEvent) {
+	if s.onReload != nil {
+		s.onReload(event)
+	}
+}
@@ -0,0 +1,225 @@
+package goscalar
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithWatch(t *testing.T) {
+	validContent := `{"openapi": "3.0.0", "info": {"title": "Test API", "version": "1.0.0"}}`
+
+	t.Run("requires a file", func(t *testing.T) {
+		scalar, err := NewScalar(WithSpecContent(validContent), WithWatch())
+		require.ErrorIs(t, err, ErrWatchRequiresFile)
+		require.Nil(t, scalar)
+	})
+
+	t.Run("builder", func(t *testing.T) {
+		specFile := filepath.Join(t.TempDir(), "openapi.json")
+		require.NoError(t, os.WriteFile(specFile, []byte(validContent), 0644))
+
+		scalar, err := NewBuilder().File(specFile).Watch().OnReload(func(ReloadEvent) {}).Build()
+		require.NoError(t, err)
+		require.True(t, scalar.watch)
+		require.NotNil(t, scalar.onReload)
+		require.NoError(t, scalar.Close())
+		require.NoError(t, scalar.Close())
+	})
+}
+
+func Test_WatchFile(t *testing.T) {
+	specFile := filepath.Join(t.TempDir(), "openapi.yaml")
+	require.NoError(t, os.WriteFile(specFile, []byte("openapi: 3.0.0\ninfo:\n  title: Version One\n"), 0644))
+
+	events := make(chan ReloadEvent, 16)
+	scalar, err := NewScalar(
+		WithFile(specFile),
+		WithWatch(),
+		WithReloadCallback(func(event ReloadEvent) { events <- event }),
+	)
+	require.NoError(t, err)
+	defer scalar.Close()
+
+	// Updated spec is swapped in
+	require.NoError(t, os.WriteFile(specFile, []byte("openapi: 3.0.0\ninfo:\n  title: Version Two\n"), 0644))
+	event := waitReloadEvent(t, events, func(event ReloadEvent) bool { return event.Changed })
+	require.NoError(t, event.Err)
+	require.Equal(t, specFile, event.Source)
+	require.Contains(t, serveSpec(t, scalar), "Version Two")
+
+	// Invalid spec keeps the last good one
+	require.NoError(t, os.WriteFile(specFile, []byte("openapi: [broken\n"), 0644))
+	event = waitReloadEvent(t, events, func(event ReloadEvent) bool { return event.Err != nil })
+	require.ErrorIs(t, event.Err, ErrInvalidSpec)
+	require.Contains(t, serveSpec(t, scalar), "Version Two")
+
+	// Replacing the file by renaming, as generators do, is picked up
+	tempFile := specFile + ".tmp"
+	require.NoError(t, os.WriteFile(tempFile, []byte("openapi: 3.0.0\ninfo:\n  title: Version Three\n"), 0644))
+	require.NoError(t, os.Rename(tempFile, specFile))
+	waitReloadEvent(t, events, func(event ReloadEvent) bool { return event.Changed })
+	require.Contains(t, serveSpec(t, scalar), "Version Three")
+
+	// Construction config is left untouched
+	require.Contains(t, scalar.config.Content, "Version One")
+}
+
+func Test_PollFile(t *testing.T) {
+	specFile := filepath.Join(t.TempDir(), "openapi.json")
+	require.NoError(t, os.WriteFile(specFile, []byte(`{"openapi":"3.0.0","info":{"title":"One"}}`), 0644))
+
+	events := make(chan ReloadEvent, 16)
+	scalar, err := NewScalar(WithFile(specFile), WithReloadCallback(func(event ReloadEvent) { events <- event }))
+	require.NoError(t, err)
+	defer scalar.Close()
+
+	scalar.interval = 10 * time.Millisecond
+	scalar.startPolling(specFile, map[string]bool{specFile: true})
+
+	require.NoError(t, os.WriteFile(specFile, []byte(`{"openapi":"3.0.0","info":{"title":"Polled Two"}}`), 0644))
+	waitReloadEvent(t, events, func(event ReloadEvent) bool { return event.Changed })
+	require.Contains(t, serveSpec(t, scalar), "Polled Two")
+
+	require.NoError(t, os.Remove(specFile))
+	event := waitReloadEvent(t, events, func(event ReloadEvent) bool { return event.Err != nil })
+	require.True(t, errors.Is(event.Err, os.ErrNotExist))
+	require.Contains(t, serveSpec(t, scalar), "Polled Two")
+}
+
+func Test_WatchBundledFiles(t *testing.T) {
+	rootContent := "openapi: 3.0.0\ninfo:\n  title: Bundled\n  version: 1.0.0\npaths: {}\ncomponents:\n  schemas:\n    Pet:\n      $ref: './schemas/%s'\n"
+
+	tests := []struct {
+		name  string
+		watch func(scalar *Scalar, specFile string)
+	}{
+		{name: "events"},
+		{
+			name: "polling",
+			watch: func(scalar *Scalar, specFile string) {
+				files := map[string]bool{}
+				for _, file := range scalar.watchFiles {
+					files[file] = true
+				}
+				scalar.interval = 10 * time.Millisecond
+				scalar.startPolling(specFile, files)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			specFile := filepath.Join(dir, "openapi.yaml")
+			require.NoError(t, os.Mkdir(filepath.Join(dir, "schemas"), 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "schemas", "pet.yaml"), []byte("type: object\ndescription: Pet One\n"), 0644))
+			require.NoError(t, os.WriteFile(specFile, []byte(fmt.Sprintf(rootContent, "pet.yaml")), 0644))
+
+			events := make(chan ReloadEvent, 16)
+			options := []Option{WithFile(specFile), WithReloadCallback(func(event ReloadEvent) { events <- event })}
+			if tt.watch == nil {
+				options = append(options, WithWatch())
+			}
+			scalar, err := NewScalar(options...)
+			require.NoError(t, err)
+			defer scalar.Close()
+			require.Equal(t, []string{specFile, filepath.Join(dir, "schemas", "pet.yaml")}, scalar.watchFiles)
+			if tt.watch != nil {
+				tt.watch(scalar, specFile)
+			}
+
+			// Changing a referenced file reloads the spec
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "schemas", "pet.yaml"), []byte("type: object\ndescription: Pet Two\n"), 0644))
+			waitReloadEvent(t, events, func(event ReloadEvent) bool { return event.Changed })
+			require.Contains(t, serveSpec(t, scalar), "Pet Two")
+
+			// A reference to a missing file fails, creating the file picks it up
+			require.NoError(t, os.WriteFile(specFile, []byte(fmt.Sprintf(rootContent, "../models/dog.yaml")), 0644))
+			event := waitReloadEvent(t, events, func(event ReloadEvent) bool { return event.Err != nil })
+			require.ErrorIs(t, event.Err, ErrDanglingRef)
+
+			require.NoError(t, os.Mkdir(filepath.Join(dir, "models"), 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "models", "dog.yaml"), []byte("type: object\ndescription: Dog\n"), 0644))
+			waitReloadEvent(t, events, func(event ReloadEvent) bool { return event.Changed })
+			require.Contains(t, serveSpec(t, scalar), "Dog")
+		})
+	}
+}
+
+func Test_FileChanged(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.json")
+	second := filepath.Join(dir, "second.json")
+	require.NoError(t, os.WriteFile(first, []byte("{}"), 0644))
+	require.NoError(t, os.WriteFile(second, []byte("{ }"), 0644))
+
+	firstInfo, firstErr := os.Stat(first)
+	secondInfo, secondErr := os.Stat(second)
+	_, missingErr := os.Stat(filepath.Join(dir, "missing.json"))
+
+	tests := []struct {
+		name      string
+		before    os.FileInfo
+		beforeErr error
+		after     os.FileInfo
+		afterErr  error
+		expected  bool
+	}{
+		{name: "same file", before: firstInfo, beforeErr: firstErr, after: firstInfo, afterErr: firstErr, expected: false},
+		{name: "different size", before: firstInfo, beforeErr: firstErr, after: secondInfo, afterErr: secondErr, expected: true},
+		{name: "removed", before: firstInfo, beforeErr: firstErr, afterErr: missingErr, expected: true},
+		{name: "created", beforeErr: missingErr, after: firstInfo, afterErr: firstErr, expected: true},
+		{name: "still missing", beforeErr: missingErr, afterErr: missingErr, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, fileChanged(tt.before, tt.beforeErr, tt.after, tt.afterErr))
+		})
+	}
+}
+
+// waitReloadEvent waits for the first reload event matching the condition
+func waitReloadEvent(t *testing.T, events <-chan ReloadEvent, condition func(ReloadEvent) bool) ReloadEvent {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if condition(event) {
+				return event
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for reload event")
+		}
+	}
+}
+
+// serveSpec returns the spec served by the handler
+func serveSpec(t *testing.T, scalar *Scalar) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	scalar.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+// servePage returns the documentation page served by the handler
+func servePage(t *testing.T, scalar *Scalar) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	scalar.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
@@ -0,0 +1,482 @@
+package goscalar
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Spec formats
+	formatJSON = "JSON"
+	formatYAML = "YAML"
+
+	// YAML tags
+	yamlNullTag  = "!!null"
+	yamlBoolTag  = "!!bool"
+	yamlIntTag   = "!!int"
+	yamlFloatTag = "!!float"
+	yamlMergeTag = "!!merge"
+
+	// Limits of alias expansion, relative to the size of the YAML document. Specs
+	// rarely repeat anchors much, alias bombs expand exponentially.
+	yamlExpansionRatio     = 16
+	yamlExpansionAllowance = 1 << 20
+)
+
+var (
+	// yamlLinePattern extracts the line number from yaml.v3 syntax errors
+	yamlLinePattern = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+)
+
+// ParseError describes why a specification could not be parsed as JSON or YAML.
+// Line and Column are 1-based; Column is 0 when the parser does not report it.
+type ParseError struct {
+	Format string
+	Line   int
+	Column int
+	Reason string
+}
+
+// Error implements the error interface
+func (e *ParseError) Error() string {
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("invalid %s spec at line %d, column %d: %s", e.Format, e.Line, e.Column, e.Reason)
+	case e.Line > 0:
+		return fmt.Sprintf("invalid %s spec at line %d: %s", e.Format, e.Line, e.Reason)
+	default:
+		return fmt.Sprintf("invalid %s spec: %s", e.Format, e.Reason)
+	}
+}
+
+// Is reports ErrInvalidSpec as the sentinel for every parse error
+func (e *ParseError) Is(target error) bool {
+	return target == ErrInvalidSpec
+}
+
+// parseSpecContent normalizes JSON or YAML specification content into a JSON string.
+// JSON input is returned as is, YAML input is converted keeping the original key order.
+func parseSpecContent(content string) (string, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", ErrInvalidSpec
+	}
+
+	if isValidJSON(content) {
+		return content, nil
+	}
+
+	// Input that looks like JSON is not read as YAML, whose flow syntax accepts
+	// malformed JSON such as missing values
+	if strings.HasPrefix(content, "{") || strings.HasPrefix(content, "[") {
+		return "", jsonParseError(content)
+	}
+	return yamlToJSON(content)
+}
+
+// jsonParseError builds a ParseError with the position of the first JSON syntax error
+func jsonParseError(content string) error {
+	var js json.RawMessage
+	err := json.Unmarshal([]byte(content), &js)
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line, column := offsetToPosition(content, syntaxErr.Offset)
+		return &ParseError{Format: formatJSON, Line: line, Column: column, Reason: syntaxErr.Error()}
+	}
+
+	reason := "unexpected content"
+	if err != nil {
+		reason = err.Error()
+	}
+	return &ParseError{Format: formatJSON, Reason: reason}
+}
+
+// offsetToPosition converts the offset of a json.SyntaxError into a 1-based line and column.
+// The offset counts the bytes read so far, so the offending byte is the one right before it.
+func offsetToPosition(content string, offset int64) (int, int) {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	if offset > 0 {
+		offset--
+	}
+
+	prefix := content[:offset]
+	line := strings.Count(prefix, "\n") + 1
+	column := len(prefix) - strings.LastIndex(prefix, "\n")
+	return line, column
+}
+
+// yamlToJSON converts a YAML document into JSON, preserving key order. The stream
+// may hold empty documents around it, but no other document.
+func yamlToJSON(content string) (string, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+
+	var document yaml.Node
+	if err := decoder.Decode(&document); err != nil {
+		return "", yamlParseError(err)
+	}
+
+	for {
+		var extra yaml.Node
+		err := decoder.Decode(&extra)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", yamlParseError(err)
+		}
+
+		switch {
+		case emptyYAMLDocument(&extra):
+		case emptyYAMLDocument(&document):
+			document = extra
+		default:
+			return "", &ParseError{
+				Format: formatYAML,
+				Line:   extra.Line,
+				Column: extra.Column,
+				Reason: "multiple documents are not supported",
+			}
+		}
+	}
+
+	root := &document
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return "", &ParseError{
+			Format: formatYAML,
+			Line:   root.Line,
+			Column: root.Column,
+			Reason: "document must be a mapping",
+		}
+	}
+
+	expansion := &yamlExpansion{
+		visiting:  map[*yaml.Node]bool{},
+		remaining: yamlExpansionRatio*countYAMLNodes(&document) + yamlExpansionAllowance,
+		maxBytes:  yamlExpansionRatio*len(content) + yamlExpansionAllowance,
+	}
+
+	var buf bytes.Buffer
+	if err := writeYAMLNode(&buf, root, expansion); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// yamlExpansion tracks the nodes visited while converting a document. Aliases
+// are expanded in place, so the visits and output are capped to reject alias
+// bombs instead of exhausting memory.
+type yamlExpansion struct {
+	visiting  map[*yaml.Node]bool // Nodes being written, to detect recursive aliases
+	remaining int                 // Nodes that may still be visited
+	maxBytes  int                 // Maximum size of the JSON output
+}
+
+// visit accounts for a visited node, failing once the limits are exceeded
+func (e *yamlExpansion) visit(buf *bytes.Buffer, node *yaml.Node) error {
+	e.remaining--
+	if e.remaining < 0 || buf.Len() > e.maxBytes {
+		return yamlNodeError(node, "aliases expand beyond the size limit")
+	}
+	return nil
+}
+
+// countYAMLNodes counts the nodes of a document without following aliases
+func countYAMLNodes(node *yaml.Node) int {
+	count := 1
+	for _, child := range node.Content {
+		count += countYAMLNodes(child)
+	}
+	return count
+}
+
+// emptyYAMLDocument reports whether a document holds nothing but a null, like the
+// documents around "---" separators
+func emptyYAMLDocument(document *yaml.Node) bool {
+	if len(document.Content) == 0 {
+		return true
+	}
+	root := document.Content[0]
+	return root.Kind == yaml.ScalarNode && root.Tag == "!!null"
+}
+
+// yamlParseError converts yaml.v3 errors into a ParseError
+func yamlParseError(err error) error {
+	message := err.Error()
+	if matches := yamlLinePattern.FindStringSubmatch(message); matches != nil {
+		line, _ := strconv.Atoi(matches[1])
+		return &ParseError{Format: formatYAML, Line: line, Reason: matches[2]}
+	}
+	return &ParseError{Format: formatYAML, Reason: strings.TrimPrefix(message, "yaml: ")}
+}
+
+// writeYAMLNode writes the JSON representation of a YAML node
+func writeYAMLNode(buf *bytes.Buffer, node *yaml.Node, expansion *yamlExpansion) error {
+	if err := expansion.visit(buf, node); err != nil {
+		return err
+	}
+	if expansion.visiting[node] {
+		return yamlNodeError(node, "recursive alias")
+	}
+	expansion.visiting[node] = true
+	defer delete(expansion.visiting, node)
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeYAMLNode(buf, node.Content[0], expansion)
+	case yaml.AliasNode:
+		return writeYAMLNode(buf, node.Alias, expansion)
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeYAMLNode(buf, item, expansion); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case yaml.MappingNode:
+		return writeYAMLMapping(buf, node, expansion)
+	case yaml.ScalarNode:
+		return writeYAMLScalar(buf, node)
+	default:
+		return yamlNodeError(node, "unsupported node")
+	}
+}
+
+// yamlPair is a key/value entry of a YAML mapping
+type yamlPair struct {
+	key   string
+	value *yaml.Node
+}
+
+// writeYAMLMapping writes a YAML mapping as a JSON object, expanding merge keys.
+// Explicit keys take precedence over merged ones, as defined by the YAML merge key spec.
+func writeYAMLMapping(buf *bytes.Buffer, node *yaml.Node, expansion *yamlExpansion) error {
+	pairs, err := collectYAMLPairs(node, expansion)
+	if err != nil {
+		return err
+	}
+
+	buf.WriteByte('{')
+	for i, pair := range pairs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := writeJSONValue(buf, pair.key); err != nil {
+			return err
+		}
+		buf.WriteByte(':')
+		if err := writeYAMLNode(buf, pair.value, expansion); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// collectYAMLPairs returns the mapping entries in document order with merge keys resolved
+func collectYAMLPairs(node *yaml.Node, expansion *yamlExpansion) ([]yamlPair, error) {
+	var (
+		pairs  []yamlPair
+		merged []yamlPair
+		index  = map[string]int{}
+	)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		// Merged mappings are collected again for every merge key referencing them
+		expansion.remaining--
+		if expansion.remaining < 0 {
+			return nil, yamlNodeError(keyNode, "aliases expand beyond the size limit")
+		}
+
+		if keyNode.Kind == yaml.ScalarNode && keyNode.ShortTag() == yamlMergeTag {
+			entries, err := collectYAMLMerge(valueNode, expansion)
+			if err != nil {
+				return nil, err
+			}
+			merged = append(merged, entries...)
+			continue
+		}
+
+		if keyNode.Kind == yaml.AliasNode {
+			keyNode = keyNode.Alias
+		}
+		if keyNode.Kind != yaml.ScalarNode {
+			return nil, yamlNodeError(keyNode, "mapping keys must be scalars")
+		}
+
+		// Later duplicates replace earlier ones, matching JSON decoders
+		if pos, ok := index[keyNode.Value]; ok {
+			pairs[pos].value = valueNode
+			continue
+		}
+		index[keyNode.Value] = len(pairs)
+		pairs = append(pairs, yamlPair{key: keyNode.Value, value: valueNode})
+	}
+
+	for _, pair := range merged {
+		if _, ok := index[pair.key]; ok {
+			continue
+		}
+		index[pair.key] = len(pairs)
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+// collectYAMLMerge returns the entries referenced by a merge key value
+func collectYAMLMerge(node *yaml.Node, expansion *yamlExpansion) ([]yamlPair, error) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		if expansion.visiting[node] {
+			return nil, yamlNodeError(node, "recursive alias")
+		}
+		return collectYAMLPairs(node, expansion)
+	case yaml.SequenceNode:
+		var pairs []yamlPair
+		for _, item := range node.Content {
+			entries, err := collectYAMLMerge(item, expansion)
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, entries...)
+		}
+		return pairs, nil
+	default:
+		return nil, yamlNodeError(node, "merge key value must be a mapping or a sequence of mappings")
+	}
+}
+
+// writeYAMLScalar writes a YAML scalar using the JSON type implied by its resolved tag
+func writeYAMLScalar(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.ShortTag() {
+	case yamlNullTag:
+		buf.WriteString("null")
+		return nil
+	case yamlBoolTag, yamlIntTag, yamlFloatTag:
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return yamlNodeError(node, err.Error())
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return yamlNodeError(node, fmt.Sprintf("value %q cannot be represented in JSON", node.Value))
+		}
+		buf.Write(data)
+		return nil
+	default:
+		return writeJSONValue(buf, node.Value)
+	}
+}
+
+// writeJSONValue writes a JSON encoded value without HTML escaping
+func writeJSONValue(buf *bytes.Buffer, value any) error {
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+	// Encode always terminates the value with a newline
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// yamlNodeError builds a ParseError pointing at the given node
+func yamlNodeError(node *yaml.Node, reason string) error {
+	return &ParseError{Format: formatYAML, Line: node.Line, Column: node.Column, Reason: reason}
+}
+
+// jsonToYAML converts a JSON document into YAML, preserving key order
+func jsonToYAML(content string) (string, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+
+	node, err := decodeJSONNode(decoder)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode JSON: %w", err)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return "", fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// decodeJSONNode reads the next JSON value from the decoder as a YAML node
+func decodeJSONNode(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if value == '{' {
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		for decoder.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			item, err := decodeJSONNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, item)
+		}
+		// Consume the closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	case json.Number:
+		tag := yamlIntTag
+		if strings.ContainsAny(value.String(), ".eE") {
+			tag = yamlFloatTag
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: yamlBoolTag, Value: strconv.FormatBool(value)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: yamlNullTag, Value: "null"}, nil
+	}
+}
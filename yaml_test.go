@@ -0,0 +1,316 @@
+package goscalar
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseSpecContent(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expected    string
+		expectError bool
+		line        int
+		column      int
+	}{
+		{
+			name:     "JSON is kept as is",
+			content:  `{"openapi": "3.0.0", "info": {"title": "Test API"}}`,
+			expected: `{"openapi": "3.0.0", "info": {"title": "Test API"}}`,
+		},
+		{
+			name: "YAML keeps key order",
+			content: `openapi: 3.0.0
+info:
+  version: 1.0.0
+  title: Test API
+paths: {}`,
+			expected: `{"openapi":"3.0.0","info":{"version":"1.0.0","title":"Test API"},"paths":{}}`,
+		},
+		{
+			name: "YAML scalar types",
+			content: `openapi: 3.1.0
+x-int: 42
+x-float: 1.5
+x-bool: true
+x-null: null
+x-yes: yes
+x-quoted: "42"
+x-date: 2025-01-01
+x-html: "<b>&</b>"`,
+			expected: `{"openapi":"3.1.0","x-int":42,"x-float":1.5,"x-bool":true,"x-null":null,"x-yes":"yes","x-quoted":"42","x-date":"2025-01-01","x-html":"<b>&</b>"}`,
+		},
+		{
+			name: "YAML sequences and block strings",
+			content: `openapi: 3.0.0
+tags:
+  - name: users
+    description: |
+      Line one
+      Line two
+  - name: pets`,
+			expected: `{"openapi":"3.0.0","tags":[{"name":"users","description":"Line one\nLine two\n"},{"name":"pets"}]}`,
+		},
+		{
+			name: "YAML anchors and merge keys",
+			content: `openapi: 3.0.0
+x-base: &base
+  type: object
+  description: base
+x-child:
+  <<: *base
+  description: child
+x-copy: *base`,
+			expected: `{"openapi":"3.0.0","x-base":{"type":"object","description":"base"},"x-child":{"description":"child","type":"object"},"x-copy":{"type":"object","description":"base"}}`,
+		},
+		{
+			name:     "YAML with leading document marker",
+			content:  "---\nopenapi: 3.0.0\n",
+			expected: `{"openapi":"3.0.0"}`,
+		},
+		{
+			name:     "YAML with empty documents around it",
+			content:  "---\n---\nopenapi: 3.0.0\n---\n# trailing\n",
+			expected: `{"openapi":"3.0.0"}`,
+		},
+		{
+			name:        "YAML flow mapping is read as JSON",
+			content:     `{openapi: 3.0.0, info: {title: Test}}`,
+			expectError: true,
+			line:        1,
+			column:      2,
+		},
+		{
+			name:        "malformed JSON valid as YAML flow syntax",
+			content:     `{"openapi":"3.0.0","info": }`,
+			expectError: true,
+			line:        1,
+			column:      28,
+		},
+		{
+			name:        "multiple YAML documents",
+			content:     "openapi: 3.0.0\n---\nopenapi: 3.1.0\n",
+			expectError: true,
+			line:        2,
+			column:      1,
+		},
+		{
+			name:        "YAML scalar document",
+			content:     "not json",
+			expectError: true,
+			line:        1,
+			column:      1,
+		},
+		{
+			name:        "YAML syntax error",
+			content:     "openapi: 3.0.0\ninfo:\n  title: Test\n bad: indent\n",
+			expectError: true,
+			line:        3,
+		},
+		{
+			name:        "YAML infinity cannot be converted",
+			content:     "openapi: 3.0.0\nx-max: .inf\n",
+			expectError: true,
+			line:        2,
+			column:      8,
+		},
+		{
+			name:        "broken JSON reports JSON position",
+			content:     "{\n  \"openapi\": \"3.0.0\",\n  \"info\": }\n}",
+			expectError: true,
+			line:        3,
+			column:      11,
+		},
+		{
+			name:        "empty content",
+			content:     "   ",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseSpecContent(tt.content)
+
+			if tt.expectError {
+				require.Error(t, err)
+				require.ErrorIs(t, err, ErrInvalidSpec)
+				if tt.line > 0 {
+					var parseErr *ParseError
+					require.True(t, errors.As(err, &parseErr))
+					require.Equal(t, tt.line, parseErr.Line)
+					require.Equal(t, tt.column, parseErr.Column)
+				}
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
+
+func Test_YAMLAliasBomb(t *testing.T) {
+	// Every level references the previous one nine times, expanding to 9^9 scalars
+	var bomb strings.Builder
+	bomb.WriteString("openapi: 3.0.0\nl0: &l0 [x, x, x, x, x, x, x, x, x]\n")
+	for level := 1; level <= 9; level++ {
+		fmt.Fprintf(&bomb, "l%d: &l%d [", level, level)
+		for i := 0; i < 9; i++ {
+			if i > 0 {
+				bomb.WriteString(", ")
+			}
+			fmt.Fprintf(&bomb, "*l%d", level-1)
+		}
+		bomb.WriteString("]\n")
+	}
+
+	// Merge keys collect the merged mappings again for every reference
+	var merges strings.Builder
+	merges.WriteString("openapi: 3.0.0\nm0: &m0 {a: 1, b: 2, c: 3}\n")
+	for level := 1; level <= 12; level++ {
+		fmt.Fprintf(&merges, "m%d: &m%d {<<: [", level, level)
+		for i := 0; i < 9; i++ {
+			if i > 0 {
+				merges.WriteString(", ")
+			}
+			fmt.Fprintf(&merges, "*m%d", level-1)
+		}
+		merges.WriteString("]}\n")
+	}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "nested aliases", content: bomb.String()},
+		{name: "nested merge keys", content: merges.String()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewScalar(WithSpecContent(tt.content))
+			require.ErrorIs(t, err, ErrInvalidSpec)
+
+			var parseErr *ParseError
+			require.True(t, errors.As(err, &parseErr))
+			require.Equal(t, formatYAML, parseErr.Format)
+			require.Contains(t, parseErr.Reason, "aliases expand beyond the size limit")
+		})
+	}
+
+	t.Run("repeated anchors within the limits", func(t *testing.T) {
+		content := "openapi: 3.0.0\nx-base: &base {type: string}\nx-fields: [*base, *base, *base]\n"
+		result, err := parseSpecContent(content)
+		require.NoError(t, err)
+		require.Equal(t, `{"openapi":"3.0.0","x-base":{"type":"string"},"x-fields":[{"type":"string"},{"type":"string"},{"type":"string"}]}`, result)
+	})
+}
+
+func Test_ParseError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *ParseError
+		expected string
+	}{
+		{
+			name:     "line and column",
+			err:      &ParseError{Format: formatJSON, Line: 2, Column: 5, Reason: "unexpected end"},
+			expected: "invalid JSON spec at line 2, column 5: unexpected end",
+		},
+		{
+			name:     "line only",
+			err:      &ParseError{Format: formatYAML, Line: 3, Reason: "did not find expected key"},
+			expected: "invalid YAML spec at line 3: did not find expected key",
+		},
+		{
+			name:     "no position",
+			err:      &ParseError{Format: formatYAML, Reason: "unknown"},
+			expected: "invalid YAML spec: unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.err.Error())
+			require.ErrorIs(t, tt.err, ErrInvalidSpec)
+		})
+	}
+}
+
+func Test_YAMLWorkflow(t *testing.T) {
+	specContent := `openapi: 3.0.0
+info:
+  title: YAML Test API
+  version: 1.0.0
+paths:
+  /users:
+    get:
+      summary: Get users
+      responses:
+        "200":
+          description: Successful response
+`
+	tempDir := t.TempDir()
+	specFile := filepath.Join(tempDir, "openapi.yaml")
+	require.NoError(t, os.WriteFile(specFile, []byte(specContent), 0644))
+
+	invalidFile := filepath.Join(tempDir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalidFile, []byte("openapi: [3.0.0\n"), 0644))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(specContent))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		option      Option
+		expectError bool
+	}{
+		{
+			name:   "WithFile",
+			option: WithFile(specFile),
+		},
+		{
+			name:   "WithURL",
+			option: WithURL(server.URL),
+		},
+		{
+			name:   "WithSpecContent",
+			option: WithSpecContent(specContent),
+		},
+		{
+			name:        "WithFile invalid YAML",
+			option:      WithFile(invalidFile),
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scalar, err := NewScalar(tt.option)
+
+			if tt.expectError {
+				require.Error(t, err)
+				require.ErrorIs(t, err, ErrInvalidSpec)
+				require.NotErrorIs(t, err, ErrSpecRequired)
+				require.Nil(t, scalar)
+			} else {
+				require.NoError(t, err)
+				require.Contains(t, scalar.config.Content, "YAML Test API")
+				require.Contains(t, scalar.config.Content, "/users")
+			}
+		})
+	}
+}